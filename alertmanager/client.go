@@ -0,0 +1,138 @@
+package alertmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Alert is an alert as accepted by the Alertmanager v2 API.
+type Alert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	StartsAt     time.Time         `json:"startsAt,omitempty"`
+	EndsAt       time.Time         `json:"endsAt,omitempty"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+// fingerprint identifies an alert by its labels.
+func (a *Alert) fingerprint() string {
+	keys := make([]string, 0, len(a.Labels))
+	for key := range a.Labels {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s=%q,", key, a.Labels[key])
+	}
+	return b.String()
+}
+
+// Client keeps the alerts of each check firing in Alertmanager. Alerts are
+// sent with an endsAt resolveTimeout in the future, so Alertmanager resolves
+// them by itself should epimetheus stop refreshing them.
+type Client struct {
+	urls           []string
+	client         *http.Client
+	resolveTimeout time.Duration
+
+	mu     sync.Mutex
+	active map[string]map[string]Alert
+}
+
+// New sends alerts to each of the Alertmanager base URLs, as every instance
+// of a highly available Alertmanager should receive them.
+func New(urls []string, resolveTimeout, timeout time.Duration) *Client {
+	return &Client{
+		urls:           urls,
+		client:         &http.Client{Timeout: timeout},
+		resolveTimeout: resolveTimeout,
+		active:         make(map[string]map[string]Alert),
+	}
+}
+
+// Update replaces the firing alerts of a check, refreshing those still firing
+// & resolving the rest. With partial set, the check could not see everything
+// it looks at, so alerts missing from alerts are refreshed rather than
+// resolved.
+func (c *Client) Update(ctx context.Context, check string, alerts []Alert, partial bool) {
+	now := time.Now()
+
+	c.mu.Lock()
+	previous := c.active[check]
+	current := make(map[string]Alert, len(alerts))
+	for _, alert := range alerts {
+		key := alert.fingerprint()
+		alert.StartsAt = now
+		if active, ok := previous[key]; ok {
+			alert.StartsAt = active.StartsAt
+		}
+		alert.EndsAt = now.Add(c.resolveTimeout)
+		current[key] = alert
+	}
+
+	var resolved []Alert
+	for key, alert := range previous {
+		if _, ok := current[key]; ok {
+			continue
+		}
+		if partial {
+			alert.EndsAt = now.Add(c.resolveTimeout)
+			current[key] = alert
+		} else {
+			alert.EndsAt = now
+			resolved = append(resolved, alert)
+		}
+	}
+	c.active[check] = current
+	c.mu.Unlock()
+
+	send := resolved
+	for _, alert := range current {
+		send = append(send, alert)
+	}
+	if len(send) == 0 {
+		return
+	}
+
+	for _, url := range c.urls {
+		if err := c.post(ctx, url, send); err != nil {
+			fmt.Fprintf(os.Stderr, "error sending %s alerts to %s: %v\n", check, url, err)
+		}
+	}
+}
+
+func (c *Client) post(ctx context.Context, url string, alerts []Alert) error {
+	body, err := json.Marshal(alerts)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		strings.TrimSuffix(url, "/")+"/api/v2/alerts", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdentityKey is the gin context key the authenticated Identity is stored at.
+const IdentityKey = "epimetheus/identity"
+
+// AllGroups grants access to every route group.
+const AllGroups = "*"
+
+// WriteSuffix marks route groups that change state, such as silences:write.
+// They are only granted by name or by AllGroups.
+const WriteSuffix = ":write"
+
+// Identity is an authenticated client & the route groups it may access. A
+// nil Groups grants access to every read-only group.
+type Identity struct {
+	Name   string
+	Groups []string
+}
+
+// Allowed reports whether the identity may access the route group.
+func (i *Identity) Allowed(group string) bool {
+	if i.Groups == nil {
+		return !strings.HasSuffix(group, WriteSuffix)
+	}
+	return slices.Contains(i.Groups, AllGroups) || slices.Contains(i.Groups, group)
+}
+
+// Authenticator identifies the client of a request. ok is false when the
+// request doesn't carry valid credentials of the kind it handles.
+type Authenticator func(c *gin.Context) (identity *Identity, ok bool)
+
+// Middleware accepts a request when any of the authenticators identifies the
+// client, storing the identity under IdentityKey & its name under
+// gin.AuthUserKey. Rejected requests are challenged with each of schemes,
+// such as Basic or Bearer, those of the enabled authenticators that have one.
+func Middleware(realm string, schemes []string, authenticators ...Authenticator) gin.HandlerFunc {
+	challenges := make([]string, 0, len(schemes))
+	for _, scheme := range schemes {
+		challenges = append(challenges, scheme+" realm="+strconv.Quote(realm))
+	}
+
+	return func(c *gin.Context) {
+		for _, authenticate := range authenticators {
+			if identity, ok := authenticate(c); ok {
+				c.Set(IdentityKey, identity)
+				c.Set(gin.AuthUserKey, identity.Name)
+				c.Next()
+				return
+			}
+		}
+
+		for _, challenge := range challenges {
+			c.Writer.Header().Add("WWW-Authenticate", challenge)
+		}
+		c.AbortWithStatus(http.StatusUnauthorized)
+	}
+}
+
+// Require rejects requests whose identity may not access the route group.
+func Require(group string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get(IdentityKey)
+		if identity, ok := value.(*Identity); !ok || !identity.Allowed(group) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "access to " + group + " denied"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// Basic authenticates against a fixed set of username & password pairs, all
+// with access to every read-only route group.
+func Basic(accounts gin.Accounts) Authenticator {
+	return func(c *gin.Context) (*Identity, bool) {
+		user, password, ok := c.Request.BasicAuth()
+		if !ok {
+			return nil, false
+		}
+
+		expected, exists := accounts[user]
+		if !exists || subtle.ConstantTimeCompare([]byte(password), []byte(expected)) != 1 {
+			return nil, false
+		}
+		return &Identity{Name: user}, true
+	}
+}
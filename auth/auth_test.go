@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		name   string
+		groups []string
+		group  string
+		want   bool
+	}{
+		{"no groups read", nil, "pod", true},
+		{"no groups write", nil, "silences" + WriteSuffix, false},
+		{"empty groups", []string{}, "pod", false},
+		{"listed", []string{"health", "pod"}, "pod", true},
+		{"not listed", []string{"health"}, "pod", false},
+		{"read does not grant write", []string{"silences"}, "silences" + WriteSuffix, false},
+		{"write listed", []string{"silences" + WriteSuffix}, "silences" + WriteSuffix, true},
+		{"all groups read", []string{AllGroups}, "pod", true},
+		{"all groups write", []string{AllGroups}, "silences" + WriteSuffix, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity := &Identity{Name: "test", Groups: tt.groups}
+			if got := identity.Allowed(tt.group); got != tt.want {
+				t.Errorf("Allowed(%q) with groups %v = %v, want %v", tt.group, tt.groups, got, tt.want)
+			}
+		})
+	}
+}
+
+// testContext returns a gin context for a request with basic credentials,
+// unless user is empty.
+func testContext(user, password string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/v1/health", nil)
+	if user != "" {
+		c.Request.SetBasicAuth(user, password)
+	}
+	return c
+}
+
+func TestBasic(t *testing.T) {
+	authenticate := Basic(gin.Accounts{"ghost": "secret"})
+
+	tests := []struct {
+		name     string
+		user     string
+		password string
+		want     bool
+	}{
+		{"valid", "ghost", "secret", true},
+		{"wrong password", "ghost", "guess", false},
+		{"unknown user", "nobody", "secret", false},
+		{"no credentials", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, ok := authenticate(testContext(tt.user, tt.password))
+			if ok != tt.want {
+				t.Fatalf("Basic() ok = %v, want %v", ok, tt.want)
+			}
+			if ok && (identity.Name != tt.user || identity.Groups != nil) {
+				t.Errorf("Basic() = %+v, want %s with read-only groups", identity, tt.user)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	basic := Basic(gin.Accounts{"ghost": "secret"})
+
+	tests := []struct {
+		name       string
+		schemes    []string
+		user       string
+		route      string
+		wantStatus int
+		wantHeader []string
+	}{
+		{"authenticated", []string{"Basic"}, "ghost", "pod", http.StatusOK, nil},
+		{"challenged", []string{"Basic", "Bearer"}, "", "pod", http.StatusUnauthorized,
+			[]string{`Basic realm="test"`, `Bearer realm="test"`}},
+		{"no challenge", nil, "", "pod", http.StatusUnauthorized, nil},
+		{"read-only", []string{"Basic"}, "ghost", "silences" + WriteSuffix, http.StatusForbidden, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/", Middleware("test", tt.schemes, basic), Require(tt.route), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.user != "" {
+				req.SetBasicAuth(tt.user, "secret")
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Values("WWW-Authenticate"); !slices.Equal(got, tt.wantHeader) {
+				t.Errorf("WWW-Authenticate = %q, want %q", got, tt.wantHeader)
+			}
+		})
+	}
+}
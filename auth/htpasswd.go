@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Htpasswd authenticates against an htpasswd style file of bcrypt hashed
+// passwords, `htpasswd -B`, with an optional third field listing the route
+// groups each user may access:
+//
+//	noc:$2y$10$...:health,service,pod
+//	admin:$2y$10$...
+//
+// Users without groups may access every read-only route group.
+type Htpasswd struct {
+	file string
+
+	mu    sync.RWMutex
+	users map[string]htpasswdUser
+	// verified caches digests of passwords that matched, bcrypt being too
+	// slow to run on every request of a polling monitor
+	verified map[string][sha256.Size]byte
+}
+
+type htpasswdUser struct {
+	hash   []byte
+	groups []string
+}
+
+func NewHtpasswd(file string) (*Htpasswd, error) {
+	h := &Htpasswd{file: file}
+	if err := h.Load(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Files returns the files to watch for changes.
+func (h *Htpasswd) Files() []string {
+	return []string{h.file}
+}
+
+func (h *Htpasswd) Load() error {
+	data, err := os.ReadFile(h.file)
+	if err != nil {
+		return fmt.Errorf("error reading htpasswd file: %w", err)
+	}
+
+	users := make(map[string]htpasswdUser)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, ":")
+		if len(fields) < 2 || len(fields) > 3 || fields[0] == "" {
+			return fmt.Errorf("%s:%d: expected user:hash[:groups]", h.file, n)
+		}
+		if _, err = bcrypt.Cost([]byte(fields[1])); err != nil {
+			return fmt.Errorf("%s:%d: user '%s' does not have a bcrypt hash: %w", h.file, n, fields[0], err)
+		}
+
+		user := htpasswdUser{hash: []byte(fields[1])}
+		if len(fields) == 3 {
+			user.groups = []string{}
+			for _, group := range strings.Split(fields[2], ",") {
+				if group = strings.TrimSpace(group); group != "" {
+					user.groups = append(user.groups, group)
+				}
+			}
+		}
+		users[fields[0]] = user
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.users = users
+	h.verified = make(map[string][sha256.Size]byte)
+
+	return nil
+}
+
+// Authenticator returns an Authenticator for the users of the file.
+func (h *Htpasswd) Authenticator() Authenticator {
+	return func(c *gin.Context) (*Identity, bool) {
+		name, password, ok := c.Request.BasicAuth()
+		if !ok {
+			return nil, false
+		}
+
+		h.mu.RLock()
+		user, exists := h.users[name]
+		digest, verified := h.verified[name]
+		h.mu.RUnlock()
+		if !exists {
+			return nil, false
+		}
+
+		sum := sha256.Sum256([]byte(password))
+		if !verified || digest != sum {
+			if bcrypt.CompareHashAndPassword(user.hash, []byte(password)) != nil {
+				return nil, false
+			}
+
+			h.mu.Lock()
+			// skip caching if the file was reloaded in the meantime
+			if current, ok := h.users[name]; ok && bytes.Equal(current.hash, user.hash) {
+				h.verified[name] = sum
+			}
+			h.mu.Unlock()
+		}
+
+		return &Identity{Name: name, Groups: user.groups}, true
+	}
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"sigs.k8s.io/yaml"
+)
+
+// Client maps the names a client certificate may carry, its subject CN or any
+// DNS, email or URI SAN, to an identity. Groups limits the route groups the
+// client may access, every read-only one when unset.
+type Client struct {
+	Identity string   `json:"identity"`
+	Names    []string `json:"names"`
+	Groups   []string `json:"groups,omitempty"`
+}
+
+// LoadClients reads a YAML or JSON list of clients.
+func LoadClients(file string) ([]Client, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading client allowlist: %w", err)
+	}
+
+	var clients []Client
+	if err = yaml.UnmarshalStrict(data, &clients); err != nil {
+		return nil, fmt.Errorf("error parsing client allowlist %s: %w", file, err)
+	}
+	for i, client := range clients {
+		if client.Identity == "" || client.Names == nil {
+			return nil, fmt.Errorf("client %d in %s needs an identity & names", i, file)
+		}
+	}
+
+	return clients, nil
+}
+
+// ClientCert authenticates requests presenting a certificate that was verified
+// against the client CA during the TLS handshake & names an allowed client.
+func ClientCert(clients []Client) Authenticator {
+	return func(c *gin.Context) (*Identity, bool) {
+		if c.Request.TLS == nil || len(c.Request.TLS.VerifiedChains) == 0 {
+			return nil, false
+		}
+
+		names := certNames(c.Request.TLS.VerifiedChains[0][0])
+		for _, client := range clients {
+			if slices.ContainsFunc(client.Names, func(name string) bool {
+				return slices.Contains(names, name)
+			}) {
+				return &Identity{Name: client.Identity, Groups: client.Groups}, true
+			}
+		}
+		return nil, false
+	}
+}
+
+func certNames(cert *x509.Certificate) []string {
+	var names []string
+	if cert.Subject.CommonName != "" {
+		names = append(names, cert.Subject.CommonName)
+	}
+	names = append(names, cert.DNSNames...)
+	names = append(names, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+	return names
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TokenReviewer validates a bearer token, returning the identity it belongs to.
+type TokenReviewer func(ctx context.Context, token string) (*Identity, error)
+
+// Bearer authenticates requests carrying an `Authorization: Bearer` token
+// accepted by review. Accepted tokens are cached for ttl so that polling
+// clients don't cost an API request each, rejected ones are not cached.
+func Bearer(review TokenReviewer, ttl time.Duration) Authenticator {
+	type entry struct {
+		identity *Identity
+		expires  time.Time
+	}
+
+	var (
+		mu    sync.Mutex
+		cache = make(map[[sha256.Size]byte]entry)
+	)
+
+	return func(c *gin.Context) (*Identity, bool) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			return nil, false
+		}
+		key := sha256.Sum256([]byte(token))
+		now := time.Now()
+
+		mu.Lock()
+		cached, ok := cache[key]
+		mu.Unlock()
+		if ok && now.Before(cached.expires) {
+			return cached.identity, true
+		}
+
+		identity, err := review(c.Request.Context(), token)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return nil, false
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for k, e := range cache {
+			if now.After(e.expires) {
+				delete(cache, k)
+			}
+		}
+		cache[key] = entry{identity: identity, expires: now.Add(ttl)}
+
+		return identity, true
+	}
+}
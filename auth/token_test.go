@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// countingReviewer accepts the token "good", counting the reviews.
+func countingReviewer(reviews *int) TokenReviewer {
+	return func(ctx context.Context, token string) (*Identity, error) {
+		*reviews++
+		if token != "good" {
+			return nil, errors.New("token rejected")
+		}
+		return &Identity{Name: "system:serviceaccount:monitoring:prometheus", Groups: []string{"metrics"}}, nil
+	}
+}
+
+func bearerContext(token string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	if token != "" {
+		c.Request.Header.Set("Authorization", "Bearer "+token)
+	}
+	return c
+}
+
+func TestBearer(t *testing.T) {
+	tests := []struct {
+		name        string
+		token       string
+		ttl         time.Duration
+		wait        time.Duration
+		want        bool
+		wantReviews int
+	}{
+		{name: "cached", token: "good", ttl: time.Hour, want: true, wantReviews: 1},
+		{name: "expired", token: "good", ttl: 10 * time.Millisecond, wait: 20 * time.Millisecond, want: true, wantReviews: 2},
+		{name: "rejected not cached", token: "bad", ttl: time.Hour, want: false, wantReviews: 2},
+		{name: "no token", token: "", ttl: time.Hour, want: false, wantReviews: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reviews int
+			authenticate := Bearer(countingReviewer(&reviews), tt.ttl)
+
+			for i := range 2 {
+				if i > 0 {
+					time.Sleep(tt.wait)
+				}
+				identity, ok := authenticate(bearerContext(tt.token))
+				if ok != tt.want {
+					t.Fatalf("attempt %d: ok = %v, want %v", i+1, ok, tt.want)
+				}
+				if ok && identity.Name != "system:serviceaccount:monitoring:prometheus" {
+					t.Errorf("attempt %d: identity = %+v", i+1, identity)
+				}
+			}
+			if reviews != tt.wantReviews {
+				t.Errorf("reviews = %d, want %d", reviews, tt.wantReviews)
+			}
+		})
+	}
+}
+
+func TestBearerScheme(t *testing.T) {
+	var reviews int
+	authenticate := Bearer(countingReviewer(&reviews), time.Hour)
+
+	c := bearerContext("")
+	c.Request.SetBasicAuth("good", "good")
+	if _, ok := authenticate(c); ok || reviews != 0 {
+		t.Errorf("basic credentials: ok = %v, reviews = %d, want neither", ok, reviews)
+	}
+}
@@ -0,0 +1,1055 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glbyers/epimetheus/etcd"
+	"github.com/glbyers/epimetheus/k8s"
+	"github.com/glbyers/epimetheus/severity"
+
+	"github.com/siderolabs/talos/pkg/machinery/api/machine"
+	"github.com/siderolabs/talos/pkg/machinery/client"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Object identifies what a problem is about, so it can be followed across
+// results. The zero Object stands for the check as a whole.
+type Object struct {
+	Node      string `json:"node,omitempty"`
+	Service   string `json:"service,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+}
+
+func (o Object) String() string {
+	var parts []string
+	for _, part := range []string{o.Node, o.Service, o.Namespace, o.Name} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// Problem is a single finding of a check.
+type Problem struct {
+	Object
+	Severity severity.Level `json:"severity"`
+	Message  string         `json:"message"`
+}
+
+// Report collects the problems found by a check & is embedded in every check
+// response. Critical problems are listed in errors, the rest in warnings.
+type Report struct {
+	Severity severity.Level `json:"severity"`
+	Errors   []string       `json:"errors"`
+	Warnings []string       `json:"warnings,omitempty"`
+	// Silenced & Maintenance list problems matched by a silence or about a
+	// node under maintenance, which don't count
+	Silenced    []string `json:"silenced,omitempty"`
+	Maintenance []string `json:"maintenance,omitempty"`
+	// Problems & the Objects that were checked, including healthy ones
+	Problems []Problem `json:"-"`
+	Objects  []Object  `json:"-"`
+}
+
+func (r *Report) Add(level severity.Level, msg string) {
+	r.AddFor(Object{}, level, msg)
+}
+
+// AddFor adds a problem about obj.
+func (r *Report) AddFor(obj Object, level severity.Level, msg string) {
+	switch level {
+	case severity.Critical:
+		r.Errors = append(r.Errors, msg)
+	case severity.Warning:
+		r.Warnings = append(r.Warnings, msg)
+	default:
+		return
+	}
+	r.Severity = max(r.Severity, level)
+	r.Problems = append(r.Problems, Problem{Object: obj, Severity: level, Message: msg})
+}
+
+// suppress moves the problems for which reason returns a reason out of the
+// errors & warnings, into list, so that they no longer count towards the
+// severity.
+func (r *Report) suppress(list *[]string, reason func(Problem) string) {
+	problems := r.Problems
+	r.Severity, r.Errors, r.Warnings, r.Problems = severity.OK, nil, nil, nil
+	for _, problem := range problems {
+		if why := reason(problem); why != "" {
+			*list = append(*list, fmt.Sprintf("%s (%s)", problem.Message, why))
+			continue
+		}
+		r.AddFor(problem.Object, problem.Severity, problem.Message)
+	}
+}
+
+// Observe records that obj was checked, so that it is known to be healthy
+// when there are no problems about it.
+func (r *Report) Observe(obj Object) {
+	r.Objects = append(r.Objects, obj)
+}
+
+// Result is the outcome of a check. Body is what the check's own route
+// renders, the Report & Perf are kept alongside so results can be aggregated
+// or rendered in other formats.
+type Result struct {
+	Check  string    `json:"-"`
+	Status int       `json:"status"`
+	Time   time.Time `json:"time"`
+	// Partial is set when the check could not see everything it looks at,
+	// so objects without problems may not be healthy
+	Partial bool `json:"-"`
+	*Report
+	Perf []Perf `json:"-"`
+	Body any    `json:"-"`
+}
+
+// Check runs a check with its default parameters.
+type Check func(ctx context.Context) *Result
+
+// unavailable is the result of a check that could not query its source
+// about obj, the zero Object when the whole check is affected. Silences &
+// node maintenance apply to it like to any other problem, a suppressed one no
+// longer failing the check.
+func (s *Server) unavailable(check string, obj Object, err error) *Result {
+	report := &Report{}
+	report.AddFor(obj, severity.Critical, err.Error())
+	s.silence(check, report)
+	s.suppressMaintenance(check, report)
+
+	status := http.StatusServiceUnavailable
+	if report.Severity < severity.Critical {
+		status = http.StatusOK
+	}
+	return &Result{
+		Check:   check,
+		Status:  status,
+		Time:    time.Now(),
+		Partial: true,
+		Report:  report,
+		Body: &struct {
+			Error string `json:"error"`
+			*Report
+		}{err.Error(), report},
+	}
+}
+
+// nodeProblems adds the errors of nodes apid could not reach as problems
+// about those nodes, so that silences & maintenance apply to them. Nothing is
+// added & it returns false unless every error in err is about a node.
+func nodeProblems(report *Report, nodeList []*k8s.Node, err error) bool {
+	var nodeErrs []*client.NodeError
+
+	var walk func(err error) bool
+	walk = func(err error) bool {
+		switch e := err.(type) {
+		case *client.NodeError:
+			nodeErrs = append(nodeErrs, e)
+			return true
+		case interface{ WrappedErrors() []error }:
+			return !slices.ContainsFunc(e.WrappedErrors(), func(err error) bool { return !walk(err) })
+		case interface{ Unwrap() []error }:
+			return !slices.ContainsFunc(e.Unwrap(), func(err error) bool { return !walk(err) })
+		case interface{ Unwrap() error }:
+			return walk(e.Unwrap())
+		}
+		return false
+	}
+	if !walk(err) || nodeErrs == nil {
+		return false
+	}
+
+	for _, nodeErr := range nodeErrs {
+		name := nodeName(nodeList, nodeErr.Node)
+		report.AddFor(Object{Node: name}, severity.Critical, fmt.Sprintf("Node '%s' did not respond: %v", name, nodeErr.Err))
+	}
+	return true
+}
+
+// newResult fails the result only when there are critical problems, so that
+// monitoring can page on the status code & still see warnings in the body.
+func newResult(check string, body any, report *Report, perf ...Perf) *Result {
+	status := http.StatusOK
+	if report.Severity == severity.Critical {
+		status = http.StatusExpectationFailed
+	}
+	return &Result{Check: check, Status: status, Time: time.Now(), Report: report, Perf: perf, Body: body}
+}
+
+// newResult applies the active silences & node maintenance to the report of
+// a check before making its result.
+func (s *Server) newResult(check string, body any, report *Report, perf ...Perf) *Result {
+	s.silence(check, report)
+	s.suppressMaintenance(check, report)
+	return newResult(check, body, report, perf...)
+}
+
+// respond renders the result as JSON, or as Nagios plugin output when asked
+// for with ?format=nagios or an Accept header preferring text/plain. The age
+// header tells clients how long ago a scheduled result was produced.
+func (s *Server) respond(c *gin.Context, result *Result) {
+	c.Header("X-Check-Severity", result.Severity.String())
+	c.Header("X-Check-Age", strconv.Itoa(int(time.Since(result.Time).Seconds())))
+	if c.Query("format") == "nagios" || c.NegotiateFormat(gin.MIMEJSON, gin.MIMEPlain) == gin.MIMEPlain {
+		c.String(result.Status, nagios(result))
+		return
+	}
+	c.IndentedJSON(result.Status, result.Body)
+}
+
+// checks are the checks contributing to the aggregate health endpoint.
+func (s *Server) checks() map[string]Check {
+	return map[string]Check{
+		"nodes": func(ctx context.Context) *Result {
+			return s.checkNodes(ctx, "")
+		},
+		"services": func(ctx context.Context) *Result {
+			return s.checkServiceList(ctx, "")
+		},
+		"etcd-status": func(ctx context.Context) *Result {
+			return s.checkEtcdStatus(ctx, etcd.DefaultThresholds())
+		},
+		"etcd-alarms":  s.checkEtcdAlarms,
+		"etcd-members": s.checkEtcdMembers,
+		"pods": func(ctx context.Context) *Result {
+			return s.checkPods(ctx, "", "", "", false, false)
+		},
+		"controlplane": s.checkControlPlane,
+		"leases": func(ctx context.Context) *Result {
+			return s.checkLeases(ctx, "")
+		},
+		"pvcs": func(ctx context.Context) *Result {
+			return s.checkVolumes(ctx, "", s.rules.Volumes.MaxUsage)
+		},
+		"jobs": func(ctx context.Context) *Result {
+			return s.checkJobs(ctx, "", "")
+		},
+		"cronjobs": func(ctx context.Context) *Result {
+			return s.checkCronJobs(ctx, "", "")
+		},
+	}
+}
+
+func (s *Server) checkNodes(_ context.Context, role string) *Result {
+	var response struct {
+		Nodes []*k8s.SimpleNode `json:"nodes"`
+		Report
+	}
+
+	nodeList, err := s.k8s.GetNodesByRole(role)
+	if err != nil {
+		return s.unavailable("nodes", Object{}, err)
+	}
+
+	var unhealthy int
+	for _, node := range nodeList {
+		response.Nodes = append(response.Nodes, &node.SimpleNode)
+		obj := Object{Node: node.Name}
+		response.Observe(obj)
+		conditions := node.FailedConditions()
+		if conditions != nil {
+			unhealthy++
+		}
+		for _, cond := range conditions {
+			response.AddFor(obj, s.rules.NodeCondition(string(cond.Type)),
+				fmt.Sprintf("Node '%s' %v: %s", node.Name, cond.Type, cond.Message))
+		}
+	}
+
+	return s.newResult("nodes", &response, &response.Report,
+		Perf{Label: "nodes", Value: float64(len(nodeList))},
+		Perf{Label: "unhealthy", Value: float64(unhealthy)})
+}
+
+// checkNodeStatus fails on the node's failing conditions. With events set,
+// the node's recent Warning events are included when it is failing.
+func (s *Server) checkNodeStatus(ctx context.Context, name string, events bool) *Result {
+	var response struct {
+		Node   *k8s.Node    `json:"node"`
+		Events []*k8s.Event `json:"events,omitempty"`
+		Report
+	}
+
+	node, err := s.k8s.GetNode(name)
+	if err != nil {
+		return s.unavailable("node", Object{Node: name}, err)
+	}
+
+	response.Node = node
+	obj := Object{Node: node.Name}
+	response.Observe(obj)
+	conditions := node.FailedConditions()
+	for _, cond := range conditions {
+		response.AddFor(obj, s.rules.NodeCondition(string(cond.Type)), fmt.Sprintf("%v: %s", cond.Type, cond.Message))
+	}
+	if events && conditions != nil {
+		nodeEvents, _, err := s.k8s.GetWarningEvents(ctx, k8s.EventFilter{
+			Kind: "Node", Name: node.Node.Name, Since: time.Now().Add(-eventWindow),
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+		}
+		response.Events = nodeEvents[:min(len(nodeEvents), maxObjectEvents)]
+	}
+
+	return s.newResult("node", &response, &response.Report,
+		Perf{Label: "failed_conditions", Value: float64(len(conditions))})
+}
+
+// checkPods fails on pods that are not ready. With events set, the recent
+// Warning events of each pod that is not ready are included.
+func (s *Server) checkPods(ctx context.Context, node, namespace, label string, static, events bool) *Result {
+	var (
+		opts     metav1.ListOptions
+		response struct {
+			Pods []*k8s.SimplePod `json:"pods"`
+			Report
+		}
+	)
+
+	if node != "" {
+		opts.FieldSelector = "spec.nodeName=" + node
+	}
+
+	if label != "" {
+		opts.LabelSelector = label
+	}
+
+	podList, err := s.k8s.GetPods(namespace, opts)
+	if err != nil {
+		return s.unavailable("pods", Object{}, err)
+	}
+
+	var (
+		ok       bool
+		notReady int
+	)
+	podEvents := s.recentEvents(ctx, namespace)
+
+	for _, pod := range podList {
+		ok = false
+		if static {
+			for _, ref := range pod.GetOwnerReferences() {
+				if ref.Kind == "Node" {
+					response.Pods = append(response.Pods, &pod.SimplePod)
+					ok = true
+				}
+			}
+		} else {
+			response.Pods = append(response.Pods, &pod.SimplePod)
+			ok = true
+		}
+
+		if ok {
+			obj := Object{Node: pod.Spec.NodeName, Namespace: pod.Namespace, Name: pod.Name}
+			response.Observe(obj)
+			for _, cond := range pod.Pod.Status.Conditions {
+				// All conditions except NodeReady should be false
+				if cond.Type == corev1.PodReady && cond.Status != corev1.ConditionTrue && cond.Reason != "PodCompleted" {
+					notReady++
+					response.AddFor(obj, s.rules.PodNotReady(time.Since(cond.LastTransitionTime.Time)),
+						fmt.Sprintf("Pod '%s/%s' not ready: %s", pod.Namespace, pod.Name, cond.Message))
+					if events {
+						pod.SimplePod.Events = podEvents("Pod", pod.Namespace, pod.Name)
+					}
+				}
+			}
+		}
+	}
+
+	return s.newResult("pods", &response, &response.Report,
+		Perf{Label: "pods", Value: float64(len(response.Pods))},
+		Perf{Label: "not_ready", Value: float64(notReady)})
+}
+
+func (s *Server) checkWorkloads(_ context.Context, kind string,
+	list func(string, metav1.ListOptions) ([]*k8s.Workload, error), namespace, label string) *Result {
+	var (
+		opts     metav1.ListOptions
+		response struct {
+			Workloads []*k8s.Workload `json:"workloads"`
+			Report
+		}
+	)
+	opts.LabelSelector = label
+
+	workloads, err := list(namespace, opts)
+	if err != nil {
+		return s.unavailable(kind, Object{}, err)
+	}
+
+	var failing int
+	response.Workloads = workloads
+	for _, workload := range workloads {
+		obj := Object{Namespace: workload.Namespace, Name: workload.Name}
+		response.Observe(obj)
+		errs, degraded := workload.Errors()
+		if errs != nil || degraded != nil {
+			failing++
+		}
+		for _, msg := range errs {
+			response.AddFor(obj, severity.Critical, msg)
+		}
+		for _, msg := range degraded {
+			response.AddFor(obj, s.rules.Workloads.Degraded, msg)
+		}
+	}
+
+	return s.newResult(kind, &response, &response.Report,
+		Perf{Label: kind, Value: float64(len(workloads))},
+		Perf{Label: "failing", Value: float64(failing)})
+}
+
+func (s *Server) checkServiceList(ctx context.Context, name string) *Result {
+	var (
+		unhealthy int
+		response  struct {
+			Services []*client.ServiceInfo `json:"services"`
+			Report
+		}
+	)
+
+	nodeList, err := s.getTalosNodes(name)
+	if err != nil {
+		return s.unavailable("services", Object{Node: name}, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	serviceList, err := s.talos.GetServiceList(ctx, addresses(nodeList))
+	if err != nil && !nodeProblems(&response.Report, nodeList, err) {
+		if serviceList == nil {
+			return s.unavailable("services", Object{Node: name}, err)
+		}
+		response.Add(severity.Critical, err.Error())
+	}
+
+	for _, nodeService := range serviceList {
+		for _, svc := range nodeService.Services {
+			info := &client.ServiceInfo{
+				Metadata: nodeService.Metadata,
+				Service:  svc,
+			}
+			response.Services = append(response.Services, info)
+			if s.serviceProblems(&response.Report, info, nodeList) {
+				unhealthy++
+			}
+		}
+	}
+
+	result := s.newResult("services", &response, &response.Report,
+		Perf{Label: "services", Value: float64(len(response.Services))},
+		Perf{Label: "unhealthy", Value: float64(unhealthy)})
+	result.Partial = err != nil
+	return result
+}
+
+func (s *Server) checkService(ctx context.Context, name, service string) *Result {
+	var (
+		unhealthy int
+		response  struct {
+			Service []client.ServiceInfo `json:"service"`
+			Report
+		}
+	)
+
+	nodeList, err := s.getTalosNodes(name)
+	if err != nil {
+		return s.unavailable("service", Object{Node: name, Service: service}, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	services, err := s.talos.GetServiceInfo(ctx, addresses(nodeList), service)
+	if err != nil && !nodeProblems(&response.Report, nodeList, err) {
+		if services == nil {
+			return s.unavailable("service", Object{Node: name, Service: service}, err)
+		}
+		response.Add(severity.Critical, err.Error())
+	}
+
+	response.Service = services
+	for _, svc := range services {
+		if s.serviceProblems(&response.Report, &svc, nodeList) {
+			unhealthy++
+		}
+	}
+
+	result := s.newResult("service", &response, &response.Report,
+		Perf{Label: "services", Value: float64(len(services))},
+		Perf{Label: "unhealthy", Value: float64(unhealthy)})
+	result.Partial = err != nil
+	return result
+}
+
+// getTalosNodes returns the named node, or all nodes when name is empty.
+func (s *Server) getTalosNodes(name string) ([]*k8s.Node, error) {
+	if name == "" {
+		return s.k8s.GetNodes()
+	}
+
+	node, err := s.k8s.GetNode(name)
+	if err != nil {
+		return nil, err
+	}
+	return []*k8s.Node{node}, nil
+}
+
+// serviceProblems applies the health rules to a service reported by one of
+// nodeList, adding any unmet requirement to the report. It returns whether
+// there was one.
+func (s *Server) serviceProblems(report *Report, svc *client.ServiceInfo, nodeList []*k8s.Node) bool {
+	var roles []string
+
+	hostname := svc.Metadata.GetHostname()
+	for _, node := range nodeList {
+		if node.Address == hostname {
+			roles = node.Roles
+		}
+	}
+	obj := Object{Node: nodeName(nodeList, hostname), Service: svc.Service.Id}
+	report.Observe(obj)
+
+	req := s.rules.Service(svc.Service.Id, roles)
+	if req.Health && !svc.Service.GetHealth().GetHealthy() {
+		report.AddFor(obj, req.Severity, fmt.Sprintf("Service '%s' on %s not healthy", svc.Service.Id, hostname))
+		return true
+	} else if req.Running && svc.Service.State != "Running" {
+		report.AddFor(obj, req.Severity, fmt.Sprintf("Service '%s' on %s not running", svc.Service.Id, hostname))
+		return true
+	}
+	return false
+}
+
+func (s *Server) checkEtcdStatus(ctx context.Context, thresholds etcd.Thresholds) *Result {
+	var (
+		members  []etcd.Member
+		perf     []Perf
+		response struct {
+			Status []*machine.EtcdStatus `json:"status"`
+			Report
+		}
+	)
+
+	// fetch internal address for control plane nodes
+	nodeList, err := s.k8s.GetNodesByRole("control-plane")
+	if err != nil {
+		return s.unavailable("etcd-status", Object{}, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	etcdStatusList, err := s.talos.GetEtcdStatus(ctx, addresses(nodeList))
+	if err != nil {
+		return s.unavailable("etcd-status", Object{}, err)
+	}
+
+	for _, etcdStatus := range etcdStatusList {
+		response.Status = append(response.Status, etcdStatus)
+		if etcdStatus.MemberStatus == nil {
+			continue
+		}
+
+		hostname := nodeName(nodeList, etcdStatus.Metadata.GetHostname())
+		response.Observe(Object{Node: hostname})
+		members = append(members, etcd.Member{Hostname: hostname, Status: etcdStatus.MemberStatus})
+		perf = append(perf,
+			Perf{Label: hostname + " db_size", Value: float64(etcdStatus.MemberStatus.DbSize), UOM: "B"},
+			Perf{Label: hostname + " db_size_in_use", Value: float64(etcdStatus.MemberStatus.DbSizeInUse), UOM: "B"})
+	}
+
+	for _, problem := range etcd.Evaluate(members, thresholds) {
+		response.AddFor(Object{Node: problem.Member}, problem.Severity, problem.Message)
+	}
+
+	return s.newResult("etcd-status", &response, &response.Report, perf...)
+}
+
+// checkEtcdMembers compares the etcd member list reported by each control
+// plane node against the control plane nodes known to Kubernetes.
+func (s *Server) checkEtcdMembers(ctx context.Context) *Result {
+	var (
+		voting   int
+		learners int
+		response struct {
+			Members []*machine.EtcdMember `json:"members"`
+			Report
+		}
+	)
+
+	nodeList, err := s.k8s.GetNodesByRole("control-plane")
+	if err != nil {
+		return s.unavailable("etcd-members", Object{}, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	memberLists, err := s.talos.GetEtcdMembers(ctx, addresses(nodeList))
+	if err != nil {
+		if memberLists == nil {
+			return s.unavailable("etcd-members", Object{}, err)
+		}
+		response.Add(severity.Critical, err.Error())
+	}
+
+	// each node reports the members it knows of, so merge them by ID
+	seen := make(map[uint64]bool)
+	for _, memberList := range memberLists {
+		for _, member := range memberList.Members {
+			if seen[member.Id] {
+				continue
+			}
+			seen[member.Id] = true
+			response.Members = append(response.Members, member)
+		}
+	}
+
+	members := make(map[string]*machine.EtcdMember)
+	for _, member := range response.Members {
+		obj := Object{Node: member.Hostname}
+		response.Observe(obj)
+		members[member.Hostname] = member
+		if member.IsLearner {
+			learners++
+			response.AddFor(obj, severity.Warning, fmt.Sprintf("Member '%s' (%x) is a learner", member.Hostname, member.Id))
+		} else {
+			voting++
+		}
+		if !slices.ContainsFunc(nodeList, func(node *k8s.Node) bool {
+			return node.Name == member.Hostname || node.Node.Name == member.Hostname
+		}) {
+			response.AddFor(obj, severity.Critical, fmt.Sprintf("Member '%s' (%x) is not a control-plane node",
+				member.Hostname, member.Id))
+		}
+	}
+
+	var available int
+	for _, node := range nodeList {
+		member, ok := members[node.Name]
+		if !ok {
+			member, ok = members[node.Node.Name]
+		}
+		if !ok {
+			response.AddFor(Object{Node: node.Name}, severity.Critical,
+				fmt.Sprintf("Control-plane node '%s' is not an etcd member", node.Name))
+		} else if !member.IsLearner && slices.ContainsFunc(memberLists, func(m *machine.EtcdMembers) bool {
+			return m.Metadata.GetHostname() == node.Address
+		}) {
+			available++
+		}
+	}
+
+	if voting > 0 && available < voting/2+1 {
+		response.Add(severity.Critical, fmt.Sprintf("Only %d of %d voting members responded, quorum is %d",
+			available, voting, voting/2+1))
+	}
+	if voting > 0 && voting%2 == 0 {
+		response.Add(severity.Warning, fmt.Sprintf("Cluster has an even number of voting members (%d)", voting))
+	} else if voting > 0 && voting < 3 {
+		response.Add(severity.Warning, fmt.Sprintf("Cluster has %d voting member, which tolerates no failures", voting))
+	}
+
+	return s.newResult("etcd-members", &response, &response.Report,
+		Perf{Label: "voting", Value: float64(voting)},
+		Perf{Label: "learners", Value: float64(learners)},
+		Perf{Label: "available", Value: float64(available)})
+}
+
+func (s *Server) checkEtcdAlarms(ctx context.Context) *Result {
+	var report Report
+
+	// fetch internal address for control plane nodes
+	nodeList, err := s.k8s.GetNodesByRole("control-plane")
+	if err != nil {
+		return s.unavailable("etcd-alarms", Object{}, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	alarms, err := s.talos.GetEtcdAlarms(ctx, addresses(nodeList))
+	if err != nil {
+		return s.unavailable("etcd-alarms", Object{}, err)
+	}
+
+	if alarms == nil {
+		return s.newResult("etcd-alarms", gin.H{"message": "No alarms present"}, &report,
+			Perf{Label: "alarms", Value: 0})
+	}
+
+	for _, alarm := range alarms {
+		report.AddFor(Object{Name: fmt.Sprintf("%x", alarm.MemberId)}, severity.Critical,
+			fmt.Sprintf("Member %x has alarm %s", alarm.MemberId, alarm.Alarm))
+	}
+	return s.newResult("etcd-alarms", alarms, &report,
+		Perf{Label: "alarms", Value: float64(len(alarms))})
+}
+
+// controlPlaneComponents run as static pods on every control-plane node.
+var controlPlaneComponents = []string{"kube-apiserver", "kube-controller-manager", "kube-scheduler"}
+
+// ControlPlaneComponent is the static pod of a component on one node.
+type ControlPlaneComponent struct {
+	Node      string `json:"node"`
+	Component string `json:"component"`
+	Pod       string `json:"pod,omitempty"`
+	Ready     bool   `json:"ready"`
+}
+
+// checkControlPlane combines the API server's own readiness & liveness checks
+// with the readiness of the control-plane static pods of each node.
+func (s *Server) checkControlPlane(ctx context.Context) *Result {
+	var response struct {
+		Readyz     []k8s.HealthCheck        `json:"readyz"`
+		Livez      []k8s.HealthCheck        `json:"livez"`
+		Components []*ControlPlaneComponent `json:"components"`
+		Report
+	}
+
+	nodeList, err := s.k8s.GetNodesByRole("control-plane")
+	if err != nil {
+		return s.unavailable("controlplane", Object{}, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	for _, endpoint := range []struct {
+		path   string
+		checks *[]k8s.HealthCheck
+	}{
+		{"/readyz", &response.Readyz},
+		{"/livez", &response.Livez},
+	} {
+		checks, ok, err := s.k8s.GetHealthChecks(ctx, endpoint.path)
+		if err != nil {
+			response.Add(severity.Critical, err.Error())
+			continue
+		}
+		*endpoint.checks = checks
+
+		obj := Object{Name: endpoint.path[1:]}
+		response.Observe(obj)
+		failed := false
+		for _, check := range checks {
+			if !check.OK {
+				failed = true
+				response.AddFor(obj, severity.Critical,
+					fmt.Sprintf("API server %s check '%s' %s", endpoint.path, check.Name, check.Message))
+			}
+		}
+		if !ok && !failed {
+			response.AddFor(obj, severity.Critical, fmt.Sprintf("API server %s failed", endpoint.path))
+		}
+	}
+
+	var notReady int
+	for _, node := range nodeList {
+		pods, err := s.k8s.GetPods("kube-system", metav1.ListOptions{FieldSelector: "spec.nodeName=" + node.Node.Name})
+		if err != nil {
+			return s.unavailable("controlplane", Object{}, err)
+		}
+
+		for _, name := range controlPlaneComponents {
+			component := &ControlPlaneComponent{Node: node.Name, Component: name}
+			response.Components = append(response.Components, component)
+
+			obj := Object{Node: node.Name, Namespace: "kube-system", Name: name}
+			response.Observe(obj)
+
+			for _, pod := range pods {
+				if strings.HasPrefix(pod.Name, name+"-") && slices.ContainsFunc(pod.GetOwnerReferences(),
+					func(ref metav1.OwnerReference) bool { return ref.Kind == "Node" }) {
+					component.Pod = pod.Name
+					component.Ready = podReady(pod.Pod)
+				}
+			}
+
+			if component.Pod == "" {
+				notReady++
+				response.AddFor(obj, severity.Critical, fmt.Sprintf("No %s pod on node '%s'", name, node.Name))
+			} else if !component.Ready {
+				notReady++
+				response.AddFor(obj, severity.Critical, fmt.Sprintf("Pod '%s' on node '%s' not ready", component.Pod, node.Name))
+			}
+		}
+	}
+
+	return s.newResult("controlplane", &response, &response.Report,
+		Perf{Label: "nodes", Value: float64(len(nodeList))},
+		Perf{Label: "components_not_ready", Value: float64(notReady)})
+}
+
+// checkLeases reports the holder of each leader-election lease in namespace,
+// or in the namespaces from the rules, failing leases that haven't been
+// renewed within their duration plus the tolerance from the rules. Leases
+// excluded by the rules are left out.
+func (s *Server) checkLeases(ctx context.Context, namespace string) *Result {
+	var response struct {
+		Leases []*k8s.Lease `json:"leases"`
+		Report
+	}
+
+	namespaces := s.rules.LeaseNamespaces()
+	if namespace != "" {
+		namespaces = []string{namespace}
+	}
+
+	// holders are matched to nodes by either name
+	hosts := make(map[string]string)
+	if nodeList, err := s.k8s.GetNodes(); err == nil {
+		for _, node := range nodeList {
+			hosts[node.Name] = node.Name
+			hosts[node.Node.Name] = node.Name
+		}
+	}
+
+	var stale int
+	for _, ns := range namespaces {
+		leases, err := s.k8s.GetLeases(ctx, ns)
+		if err != nil {
+			return s.unavailable("leases", Object{}, err)
+		}
+
+		for _, lease := range leases {
+			if s.rules.LeaseExcluded(lease.Name) {
+				continue
+			}
+			lease.Node = hosts[lease.HolderHost()]
+			response.Leases = append(response.Leases, lease)
+
+			obj := Object{Node: lease.Node, Namespace: lease.Namespace, Name: lease.Name}
+			response.Observe(obj)
+			if lease.Holder == "" {
+				response.AddFor(obj, severity.Warning, fmt.Sprintf("Lease '%s/%s' has no holder", lease.Namespace, lease.Name))
+				continue
+			}
+			// a holder that never renewed may still be acquiring the lease
+			if lease.RenewTime.IsZero() {
+				response.AddFor(obj, severity.Warning, fmt.Sprintf("Lease '%s/%s' held by '%s' has never been renewed",
+					lease.Namespace, lease.Name, lease.Holder))
+				continue
+			}
+			if age := time.Since(lease.RenewTime); age > lease.Duration()+s.rules.Leases.Tolerance.Duration {
+				stale++
+				response.AddFor(obj, severity.Critical, fmt.Sprintf("Lease '%s/%s' held by '%s' not renewed for %s",
+					lease.Namespace, lease.Name, lease.Holder, age.Round(time.Second)))
+			}
+		}
+	}
+
+	return s.newResult("leases", &response, &response.Report,
+		Perf{Label: "leases", Value: float64(len(response.Leases))},
+		Perf{Label: "stale", Value: float64(stale)})
+}
+
+// checkVolumes fails on claims that are lost, pending for longer than the
+// rules allow or waiting on a file system resize & on failed or released
+// volumes, warning about volumes more than maxUsage full. Resizing claims &
+// released volumes are only warnings when the rules say so. Volume usage is
+// only fetched from the kubelets when maxUsage is set.
+func (s *Server) checkVolumes(ctx context.Context, namespace string, maxUsage float64) *Result {
+	var response struct {
+		Claims  []*k8s.VolumeClaim      `json:"claims"`
+		Volumes []*k8s.PersistentVolume `json:"volumes"`
+		Report
+	}
+
+	claims, err := s.k8s.GetVolumeClaims(namespace)
+	if err != nil {
+		return s.unavailable("pvcs", Object{}, err)
+	}
+	volumes, err := s.k8s.GetPersistentVolumes(namespace)
+	if err != nil {
+		return s.unavailable("pvcs", Object{}, err)
+	}
+	response.Claims = claims
+	response.Volumes = volumes
+
+	usage := make(map[string]*k8s.VolumeUsage)
+	if maxUsage > 0 {
+		nodeList, err := s.k8s.GetNodes()
+		if err != nil {
+			return s.unavailable("pvcs", Object{}, err)
+		}
+		for _, node := range nodeList {
+			nodeUsage, err := s.k8s.GetVolumeUsage(ctx, node.Node.Name)
+			if err != nil {
+				response.AddFor(Object{Node: node.Name}, severity.Warning, err.Error())
+				continue
+			}
+			for key, u := range nodeUsage {
+				u.Node = node.Name
+				usage[key] = u
+			}
+		}
+	}
+
+	var failing int
+	for _, claim := range claims {
+		obj := Object{Namespace: claim.Namespace, Name: claim.Name}
+		response.Observe(obj)
+		claim.Usage = usage[claim.Namespace+"/"+claim.Name]
+
+		problems := len(response.Problems)
+		switch corev1.PersistentVolumeClaimPhase(claim.Phase) {
+		case corev1.ClaimLost:
+			response.AddFor(obj, severity.Critical,
+				fmt.Sprintf("Claim '%s/%s' lost its volume '%s'", claim.Namespace, claim.Name, claim.Volume))
+		case corev1.ClaimPending:
+			if claim.WaitingForConsumer {
+				break
+			}
+			pending := time.Since(claim.Created)
+			response.AddFor(obj, s.rules.ClaimPending(pending),
+				fmt.Sprintf("Claim '%s/%s' pending for %s", claim.Namespace, claim.Name, pending.Round(time.Second)))
+		}
+		if claim.ResizePending {
+			response.AddFor(obj, s.rules.Volumes.ResizePending,
+				fmt.Sprintf("Claim '%s/%s' waiting for its file system to be resized", claim.Namespace, claim.Name))
+		}
+		if claim.Usage != nil && claim.Usage.Ratio() > maxUsage {
+			response.AddFor(obj, severity.Warning, fmt.Sprintf("Claim '%s/%s' on node '%s' is %.0f%% full",
+				claim.Namespace, claim.Name, claim.Usage.Node, claim.Usage.Ratio()*100))
+		}
+		if len(response.Problems) > problems {
+			failing++
+		}
+	}
+
+	for _, volume := range volumes {
+		obj := Object{Name: volume.Name}
+		response.Observe(obj)
+
+		switch corev1.PersistentVolumePhase(volume.Phase) {
+		case corev1.VolumeFailed:
+			failing++
+			response.AddFor(obj, severity.Critical, fmt.Sprintf("Volume '%s' failed: %s", volume.Name, volume.Message))
+		case corev1.VolumeReleased:
+			failing++
+			response.AddFor(obj, s.rules.Volumes.Released,
+				fmt.Sprintf("Volume '%s' released by claim '%s' but not reclaimed", volume.Name, volume.Claim))
+		}
+	}
+
+	return s.newResult("pvcs", &response, &response.Report,
+		Perf{Label: "claims", Value: float64(len(claims))},
+		Perf{Label: "volumes", Value: float64(len(volumes))},
+		Perf{Label: "failing", Value: float64(failing)})
+}
+
+// checkJobs fails on jobs with a Failed condition, unless the CronJob that
+// created them has succeeded since, & on jobs still running past their
+// activeDeadlineSeconds.
+func (s *Server) checkJobs(_ context.Context, namespace, label string) *Result {
+	var response struct {
+		Jobs []*k8s.Job `json:"jobs"`
+		Report
+	}
+
+	jobs, err := s.k8s.GetJobs(namespace, metav1.ListOptions{LabelSelector: label})
+	if err != nil {
+		return s.unavailable("jobs", Object{}, err)
+	}
+	cronJobs, err := s.k8s.GetCronJobs(namespace, metav1.ListOptions{})
+	if err != nil {
+		return s.unavailable("jobs", Object{}, err)
+	}
+	lastSuccessful := make(map[string]*time.Time)
+	for _, cronJob := range cronJobs {
+		lastSuccessful[cronJob.Namespace+"/"+cronJob.Name] = cronJob.LastSuccessful
+	}
+
+	var failed, overdue int
+	now := time.Now()
+	response.Jobs = jobs
+	for _, job := range jobs {
+		obj := Object{Namespace: job.Namespace, Name: job.Name}
+		response.Observe(obj)
+
+		if job.Failure != "" {
+			// a later run of the same CronJob succeeded
+			if last := lastSuccessful[job.Namespace+"/"+job.CronJob]; job.CronJob != "" && last != nil &&
+				job.FailedAt != nil && last.After(*job.FailedAt) {
+				continue
+			}
+			failed++
+			response.AddFor(obj, severity.Critical, fmt.Sprintf("Job '%s/%s' failed: %s", job.Namespace, job.Name, job.Failure))
+		} else if d := job.Overdue(now); d > 0 {
+			overdue++
+			response.AddFor(obj, severity.Critical, fmt.Sprintf("Job '%s/%s' running %s past its deadline of %ds",
+				job.Namespace, job.Name, d.Round(time.Second), job.DeadlineSeconds))
+		}
+	}
+
+	return s.newResult("jobs", &response, &response.Report,
+		Perf{Label: "jobs", Value: float64(len(jobs))},
+		Perf{Label: "failed", Value: float64(failed)},
+		Perf{Label: "overdue", Value: float64(overdue)})
+}
+
+// checkCronJobs fails on CronJobs that haven't succeeded within the number of
+// schedule intervals set by the rules, counting from their creation when they
+// never have, & warns about suspended ones.
+func (s *Server) checkCronJobs(_ context.Context, namespace, label string) *Result {
+	var response struct {
+		CronJobs []*k8s.CronJob `json:"cronJobs"`
+		Report
+	}
+
+	cronJobs, err := s.k8s.GetCronJobs(namespace, metav1.ListOptions{LabelSelector: label})
+	if err != nil {
+		return s.unavailable("cronjobs", Object{}, err)
+	}
+
+	var stale, suspended int
+	now := time.Now()
+	response.CronJobs = cronJobs
+	for _, cronJob := range cronJobs {
+		obj := Object{Namespace: cronJob.Namespace, Name: cronJob.Name}
+		response.Observe(obj)
+
+		if cronJob.Suspended {
+			suspended++
+			response.AddFor(obj, severity.Warning, fmt.Sprintf("CronJob '%s/%s' is suspended", cronJob.Namespace, cronJob.Name))
+			continue
+		}
+
+		since, what := cronJob.Created, "has not succeeded since it was created"
+		if cronJob.LastSuccessful != nil {
+			since, what = *cronJob.LastSuccessful, "last succeeded"
+		}
+		interval, err := cronJob.Interval(since)
+		if err != nil {
+			response.AddFor(obj, severity.Warning, err.Error())
+			continue
+		}
+		if age := now.Sub(since); age > time.Duration(s.rules.CronJobs.MissedSchedules*float64(interval)) {
+			stale++
+			response.AddFor(obj, severity.Critical, fmt.Sprintf("CronJob '%s/%s' %s %s ago, scheduled every %s",
+				cronJob.Namespace, cronJob.Name, what, age.Round(time.Minute), interval))
+		}
+	}
+
+	return s.newResult("cronjobs", &response, &response.Report,
+		Perf{Label: "cronjobs", Value: float64(len(cronJobs))},
+		Perf{Label: "stale", Value: float64(stale)},
+		Perf{Label: "suspended", Value: float64(suspended)})
+}
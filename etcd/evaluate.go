@@ -0,0 +1,142 @@
+package etcd
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/alecthomas/units"
+	"github.com/dustin/go-humanize"
+	"github.com/glbyers/epimetheus/severity"
+
+	"github.com/siderolabs/talos/pkg/machinery/api/machine"
+)
+
+// Thresholds configure the etcd status evaluation.
+type Thresholds struct {
+	// MinDbSize is the database size below which fragmentation is ignored.
+	MinDbSize units.Base2Bytes
+	// MaxFragmentation is the ratio of unused to total database size above
+	// which a member is reported.
+	MaxFragmentation float64
+	// Quota is the etcd backend quota, --quota-backend-bytes.
+	Quota units.Base2Bytes
+	// QuotaWarning & QuotaCritical are ratios of Quota.
+	QuotaWarning  float64
+	QuotaCritical float64
+	// MaxRaftLag is how far a member's raft index may trail the highest.
+	MaxRaftLag uint64
+}
+
+func DefaultThresholds() Thresholds {
+	return Thresholds{
+		MinDbSize:        512 * units.MiB,
+		MaxFragmentation: 0.5,
+		Quota:            2 * units.GiB,
+		QuotaWarning:     0.8,
+		QuotaCritical:    0.95,
+		MaxRaftLag:       1000,
+	}
+}
+
+// Member is the status of one etcd member & the name of its node.
+type Member struct {
+	Hostname string
+	Status   *machine.EtcdMemberStatus
+}
+
+// Problem is a single finding about one member.
+type Problem struct {
+	Member   string
+	Severity severity.Level
+	Message  string
+}
+
+// Fragmentation returns the ratio of the database that is allocated but not
+// in use.
+func Fragmentation(status *machine.EtcdMemberStatus) float64 {
+	if status.DbSize <= 0 {
+		return 0
+	}
+	return float64(status.DbSize-status.DbSizeInUse) / float64(status.DbSize)
+}
+
+// Evaluate checks leader agreement, member errors, database fragmentation,
+// quota usage & raft index lag of the given members.
+func Evaluate(members []Member, t Thresholds) (problems []Problem) {
+	add := func(member Member, level severity.Level, format string, args ...any) {
+		problems = append(problems, Problem{
+			Member:   member.Hostname,
+			Severity: level,
+			Message:  fmt.Sprintf("Member '%s' ", member.Hostname) + fmt.Sprintf(format, args...),
+		})
+	}
+
+	leader := majorityLeader(members)
+
+	var maxRaftIndex uint64
+	for _, member := range members {
+		maxRaftIndex = max(maxRaftIndex, member.Status.RaftIndex)
+	}
+
+	for _, member := range members {
+		status := member.Status
+
+		for _, err := range status.Errors {
+			add(member, severity.Critical, "reports error: %s", err)
+		}
+
+		if status.Leader == 0 {
+			add(member, severity.Critical, "has no leader")
+		} else if status.Leader != leader {
+			add(member, severity.Critical, "reports leader %x, other members report %x", status.Leader, leader)
+		}
+
+		if status.DbSize > int64(t.MinDbSize) {
+			if fragmentation := Fragmentation(status); fragmentation > t.MaxFragmentation {
+				add(member, severity.Warning, "db is %.0f%% fragmented, %s of %s in use",
+					fragmentation*100, humanize.IBytes(uint64(status.DbSizeInUse)), humanize.IBytes(uint64(status.DbSize)))
+			}
+		}
+
+		if t.Quota > 0 {
+			usage := float64(status.DbSize) / float64(t.Quota)
+			if usage >= t.QuotaCritical {
+				add(member, severity.Critical, "db size %s is %.0f%% of the %s quota",
+					humanize.IBytes(uint64(status.DbSize)), usage*100, humanize.IBytes(uint64(t.Quota)))
+			} else if usage >= t.QuotaWarning {
+				add(member, severity.Warning, "db size %s is %.0f%% of the %s quota",
+					humanize.IBytes(uint64(status.DbSize)), usage*100, humanize.IBytes(uint64(t.Quota)))
+			}
+		}
+
+		if lag := maxRaftIndex - status.RaftIndex; lag > t.MaxRaftLag {
+			add(member, severity.Warning, "raft index %d trails the highest, %d, by %d",
+				status.RaftIndex, maxRaftIndex, lag)
+		}
+	}
+
+	return problems
+}
+
+// majorityLeader returns the leader reported by most members.
+func majorityLeader(members []Member) (leader uint64) {
+	votes := make(map[uint64]int)
+	for _, member := range members {
+		if member.Status.Leader != 0 {
+			votes[member.Status.Leader]++
+		}
+	}
+
+	ids := make([]uint64, 0, len(votes))
+	for id := range votes {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	for _, id := range ids {
+		if votes[id] > votes[leader] {
+			leader = id
+		}
+	}
+	return leader
+}
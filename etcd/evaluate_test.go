@@ -0,0 +1,208 @@
+package etcd
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/alecthomas/units"
+	"github.com/glbyers/epimetheus/severity"
+
+	"github.com/siderolabs/talos/pkg/machinery/api/machine"
+)
+
+func member(hostname string, leader, raftIndex uint64, dbSize, dbSizeInUse units.Base2Bytes) Member {
+	return Member{
+		Hostname: hostname,
+		Status: &machine.EtcdMemberStatus{
+			Leader:      leader,
+			RaftIndex:   raftIndex,
+			DbSize:      int64(dbSize),
+			DbSizeInUse: int64(dbSizeInUse),
+		},
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	defaults := DefaultThresholds()
+	noQuota := defaults
+	noQuota.Quota = 0
+	noMinDbSize := defaults
+	noMinDbSize.MinDbSize = 0
+
+	withError := member("cp-2", 1, 100, 64*units.MiB, 64*units.MiB)
+	withError.Status.Errors = []string{"NOSPACE"}
+
+	tests := []struct {
+		name       string
+		members    []Member
+		thresholds Thresholds
+		want       []Problem
+	}{
+		{
+			name: "healthy",
+			members: []Member{
+				member("cp-1", 1, 100, 64*units.MiB, 64*units.MiB),
+				member("cp-2", 1, 100, 64*units.MiB, 64*units.MiB),
+				member("cp-3", 1, 100, 64*units.MiB, 64*units.MiB),
+			},
+			thresholds: defaults,
+		},
+		{
+			name: "fragmented",
+			members: []Member{
+				member("cp-1", 1, 100, units.GiB, 256*units.MiB),
+				member("cp-2", 1, 100, units.GiB, units.GiB),
+			},
+			thresholds: defaults,
+			want: []Problem{
+				{"cp-1", severity.Warning, "Member 'cp-1' db is 75% fragmented, 256 MiB of 1.0 GiB in use"},
+			},
+		},
+		{
+			name: "fragmented below MinDbSize",
+			members: []Member{
+				member("cp-1", 1, 100, 256*units.MiB, 16*units.MiB),
+			},
+			thresholds: defaults,
+		},
+		{
+			name: "fragmentation at the threshold",
+			members: []Member{
+				member("cp-1", 1, 100, units.GiB, 512*units.MiB),
+			},
+			thresholds: defaults,
+		},
+		{
+			name: "empty database",
+			members: []Member{
+				member("cp-1", 1, 100, 0, 0),
+			},
+			thresholds: noMinDbSize,
+		},
+		{
+			name: "quota warning",
+			members: []Member{
+				member("cp-1", 1, 100, 1741*units.MiB, 1741*units.MiB),
+			},
+			thresholds: defaults,
+			want: []Problem{
+				{"cp-1", severity.Warning, "Member 'cp-1' db size 1.7 GiB is 85% of the 2.0 GiB quota"},
+			},
+		},
+		{
+			name: "quota critical",
+			members: []Member{
+				member("cp-1", 1, 100, 1997*units.MiB, 1997*units.MiB),
+			},
+			thresholds: defaults,
+			want: []Problem{
+				{"cp-1", severity.Critical, "Member 'cp-1' db size 2.0 GiB is 98% of the 2.0 GiB quota"},
+			},
+		},
+		{
+			name: "quota unset",
+			members: []Member{
+				member("cp-1", 1, 100, 1997*units.MiB, 1997*units.MiB),
+			},
+			thresholds: noQuota,
+		},
+		{
+			name: "raft lag",
+			members: []Member{
+				member("cp-1", 1, 5000, 64*units.MiB, 64*units.MiB),
+				member("cp-2", 1, 4000, 64*units.MiB, 64*units.MiB),
+				member("cp-3", 1, 3999, 64*units.MiB, 64*units.MiB),
+			},
+			thresholds: defaults,
+			want: []Problem{
+				{"cp-3", severity.Warning, "Member 'cp-3' raft index 3999 trails the highest, 5000, by 1001"},
+			},
+		},
+		{
+			name: "leader disagreement",
+			members: []Member{
+				member("cp-1", 1, 100, 64*units.MiB, 64*units.MiB),
+				member("cp-2", 2, 100, 64*units.MiB, 64*units.MiB),
+				member("cp-3", 1, 100, 64*units.MiB, 64*units.MiB),
+			},
+			thresholds: defaults,
+			want: []Problem{
+				{"cp-2", severity.Critical, "Member 'cp-2' reports leader 2, other members report 1"},
+			},
+		},
+		{
+			name: "leader tie picks the lowest ID",
+			members: []Member{
+				member("cp-1", 0xb, 100, 64*units.MiB, 64*units.MiB),
+				member("cp-2", 0xa, 100, 64*units.MiB, 64*units.MiB),
+			},
+			thresholds: defaults,
+			want: []Problem{
+				{"cp-1", severity.Critical, "Member 'cp-1' reports leader b, other members report a"},
+			},
+		},
+		{
+			name: "no leader",
+			members: []Member{
+				member("cp-1", 1, 100, 64*units.MiB, 64*units.MiB),
+				member("cp-2", 0, 100, 64*units.MiB, 64*units.MiB),
+			},
+			thresholds: defaults,
+			want: []Problem{
+				{"cp-2", severity.Critical, "Member 'cp-2' has no leader"},
+			},
+		},
+		{
+			name: "member errors",
+			members: []Member{
+				member("cp-1", 1, 100, 64*units.MiB, 64*units.MiB),
+				withError,
+			},
+			thresholds: defaults,
+			want: []Problem{
+				{"cp-2", severity.Critical, "Member 'cp-2' reports error: NOSPACE"},
+			},
+		},
+		{
+			name: "problems of each member",
+			members: []Member{
+				member("cp-1", 1, 100, units.GiB, 256*units.MiB),
+				member("cp-2", 0, 100, 64*units.MiB, 64*units.MiB),
+				member("cp-3", 1, 2000, 64*units.MiB, 64*units.MiB),
+			},
+			thresholds: defaults,
+			want: []Problem{
+				{"cp-1", severity.Warning, "Member 'cp-1' db is 75% fragmented, 256 MiB of 1.0 GiB in use"},
+				{"cp-1", severity.Warning, "Member 'cp-1' raft index 100 trails the highest, 2000, by 1900"},
+				{"cp-2", severity.Critical, "Member 'cp-2' has no leader"},
+				{"cp-2", severity.Warning, "Member 'cp-2' raft index 100 trails the highest, 2000, by 1900"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Evaluate(tt.members, tt.thresholds); !slices.Equal(got, tt.want) {
+				t.Errorf("Evaluate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFragmentation(t *testing.T) {
+	tests := []struct {
+		dbSize, dbSizeInUse int64
+		want                float64
+	}{
+		{0, 0, 0},
+		{100, 100, 0},
+		{100, 25, 0.75},
+	}
+
+	for _, tt := range tests {
+		status := &machine.EtcdMemberStatus{DbSize: tt.dbSize, DbSizeInUse: tt.dbSizeInUse}
+		if got := Fragmentation(status); got != tt.want {
+			t.Errorf("Fragmentation(%d, %d) = %v, want %v", tt.dbSize, tt.dbSizeInUse, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event is a state transition of a node condition, Talos service, pod or etcd
+// alarm.
+type Event struct {
+	Time      time.Time `json:"time"`
+	Type      string    `json:"type"`
+	Node      string    `json:"node,omitempty"`
+	Service   string    `json:"service,omitempty"`
+	Namespace string    `json:"namespace,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	From      string    `json:"from,omitempty"`
+	To        string    `json:"to"`
+	Message   string    `json:"message,omitempty"`
+}
+
+// Event types.
+const (
+	NodeCondition = "node-condition"
+	Service       = "service"
+	PodReady      = "pod-ready"
+	EtcdAlarm     = "etcd-alarm"
+)
+
+// Broker fans events out to subscribers.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{subscribers: make(map[chan Event]struct{})}
+}
+
+// Subscribe returns a channel receiving every event published until
+// Unsubscribe is called with it.
+func (b *Broker) Subscribe() chan Event {
+	ch := make(chan Event, 64)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[ch] = struct{}{}
+
+	return ch
+}
+
+func (b *Broker) Unsubscribe(ch chan Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.subscribers, ch)
+}
+
+// Publish sends the event to every subscriber. Subscribers that have fallen
+// behind miss the event rather than blocking the publisher.
+func (b *Broker) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
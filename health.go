@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glbyers/epimetheus/severity"
+)
+
+// HealthCheck is a single check in the aggregate health response.
+type HealthCheck struct {
+	*Result
+	// Ignored checks are run & reported but don't affect the overall status.
+	Ignored bool `json:"ignored,omitempty"`
+}
+
+// getHealth collects the scheduled result of every check, running those
+// without one concurrently. ?include= & ?exclude= take comma separated check
+// names and select which checks contribute to the status.
+func (s *Server) getHealth(c *gin.Context) {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		response struct {
+			Checks map[string]*HealthCheck `json:"checks"`
+			Report
+		}
+	)
+
+	checks := s.checks()
+	include, err := checkNames(checks, c.Query("include"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	exclude, err := checkNames(checks, c.Query("exclude"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response.Checks = make(map[string]*HealthCheck, len(checks))
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := s.cached(c, name, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			response.Checks[name] = &HealthCheck{
+				Result:  result,
+				Ignored: (include != nil && !slices.Contains(include, name)) || slices.Contains(exclude, name),
+			}
+		}()
+	}
+	wg.Wait()
+
+	names := make([]string, 0, len(response.Checks))
+	for name := range response.Checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var failed int
+	for _, name := range names {
+		check := response.Checks[name]
+		if check.Ignored {
+			continue
+		}
+		if check.Severity == severity.Critical {
+			failed++
+		}
+		for _, checkErr := range check.Errors {
+			response.Add(severity.Critical, fmt.Sprintf("%s: %s", name, checkErr))
+		}
+		for _, warning := range check.Warnings {
+			response.Add(severity.Warning, fmt.Sprintf("%s: %s", name, warning))
+		}
+	}
+
+	s.respond(c, newResult("health", &response, &response.Report,
+		Perf{Label: "checks", Value: float64(len(names))},
+		Perf{Label: "failed", Value: float64(failed)}))
+}
+
+// checkNames parses a comma separated list of check names, returning nil when
+// the list is empty.
+func checkNames(checks map[string]Check, list string) ([]string, error) {
+	if list == "" {
+		return nil, nil
+	}
+
+	names := strings.Split(list, ",")
+	for _, name := range names {
+		if _, ok := checks[name]; !ok {
+			return nil, fmt.Errorf("unknown check '%s'", name)
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glbyers/epimetheus/severity"
+)
+
+// Entry is the state of a check or of an object at a point in time.
+type Entry struct {
+	Time     time.Time      `json:"time"`
+	Severity severity.Level `json:"severity"`
+	Messages []string       `json:"messages,omitempty"`
+}
+
+// ring keeps the last size items added.
+type ring[T any] struct {
+	items []T
+	next  int
+	size  int
+	// dropped is set once an item has been overwritten
+	dropped bool
+}
+
+func newRing[T any](size int) *ring[T] {
+	return &ring[T]{size: size}
+}
+
+func (r *ring[T]) Add(item T) {
+	if len(r.items) < r.size {
+		r.items = append(r.items, item)
+		return
+	}
+	r.items[r.next] = item
+	r.next = (r.next + 1) % r.size
+	r.dropped = true
+}
+
+// Items returns the items oldest first.
+func (r *ring[T]) Items() []T {
+	items := make([]T, 0, len(r.items))
+	items = append(items, r.items[r.next:]...)
+	return append(items, r.items[:r.next]...)
+}
+
+func (r *ring[T]) Last() (last T, ok bool) {
+	if len(r.items) == 0 {
+		return last, false
+	}
+	return r.items[(r.next+len(r.items)-1)%len(r.items)], true
+}
+
+// objectHistory holds the state changes of one object.
+type objectHistory struct {
+	object   Object
+	lastSeen time.Time
+	changes  *ring[Entry]
+}
+
+// History keeps the recent results of scheduled checks & the state changes of
+// each object they check, to tell objects flapping between states apart from
+// ones that happen to look healthy when polled.
+type History struct {
+	size        int
+	flapChanges int
+	flapWindow  time.Duration
+	retention   time.Duration
+
+	mu      sync.RWMutex
+	checks  map[string]*ring[Entry]
+	objects map[string]map[string]*objectHistory
+}
+
+// NewHistory keeps size results per check & size state changes per object.
+// Objects are forgotten once they haven't been seen for retention & reported
+// as flapping when they changed state more than flapChanges times within
+// flapWindow.
+func NewHistory(size, flapChanges int, flapWindow, retention time.Duration) *History {
+	return &History{
+		size:        size,
+		flapChanges: flapChanges,
+		flapWindow:  flapWindow,
+		retention:   retention,
+		checks:      make(map[string]*ring[Entry]),
+		objects:     make(map[string]map[string]*objectHistory),
+	}
+}
+
+// Record adds a result of the named check & warns about flapping objects in
+// its report. It is registered as a Scheduler hook.
+func (h *History) Record(name string, result *Result) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.checks[name] == nil {
+		h.checks[name] = newRing[Entry](h.size)
+		h.objects[name] = make(map[string]*objectHistory)
+	}
+	h.checks[name].Add(Entry{
+		Time:     result.Time,
+		Severity: result.Severity,
+		Messages: append(append([]string(nil), result.Errors...), result.Warnings...),
+	})
+
+	// current state of every object, healthy unless there are problems about it
+	objects := make(map[string]Object)
+	states := make(map[string]Entry)
+	for _, obj := range result.Objects {
+		objects[obj.String()] = obj
+		states[obj.String()] = Entry{Time: result.Time, Severity: severity.OK}
+	}
+	for _, problem := range result.Problems {
+		if problem.Object == (Object{}) {
+			continue
+		}
+		key := problem.Object.String()
+		state, ok := states[key]
+		if !ok {
+			objects[key] = problem.Object
+			state = Entry{Time: result.Time}
+		}
+		state.Severity = max(state.Severity, problem.Severity)
+		state.Messages = append(state.Messages, problem.Message)
+		states[key] = state
+	}
+
+	var flapping []string
+	for key, state := range states {
+		object, ok := h.objects[name][key]
+		if !ok {
+			object = &objectHistory{object: objects[key], changes: newRing[Entry](h.size)}
+			h.objects[name][key] = object
+		}
+		object.lastSeen = result.Time
+
+		if last, ok := object.changes.Last(); !ok || last.Severity != state.Severity {
+			object.changes.Add(state)
+		}
+		if h.flapping(object, result.Time) {
+			flapping = append(flapping, key)
+		}
+	}
+
+	for key, object := range h.objects[name] {
+		if result.Time.Sub(object.lastSeen) > h.retention {
+			delete(h.objects[name], key)
+		}
+	}
+
+	sort.Strings(flapping)
+	for _, key := range flapping {
+		result.AddFor(objects[key], severity.Warning, fmt.Sprintf("'%s' is flapping, %d state changes in %s",
+			key, h.recentChanges(h.objects[name][key], result.Time), h.flapWindow))
+	}
+}
+
+// recentChanges counts the state changes of object within the flap window.
+// The first entry is when the object was first seen rather than a change,
+// unless older entries have been dropped.
+func (h *History) recentChanges(object *objectHistory, now time.Time) (changes int) {
+	entries := object.changes.Items()
+	first := 1
+	if object.changes.dropped {
+		first = 0
+	}
+	for _, entry := range entries[min(first, len(entries)):] {
+		if now.Sub(entry.Time) <= h.flapWindow {
+			changes++
+		}
+	}
+	return changes
+}
+
+func (h *History) flapping(object *objectHistory, now time.Time) bool {
+	return h.recentChanges(object, now) > h.flapChanges
+}
+
+// ObjectHistory is the state of one object & its recent changes.
+type ObjectHistory struct {
+	Key      string         `json:"key"`
+	Object   Object         `json:"object"`
+	Severity severity.Level `json:"severity"`
+	LastSeen time.Time      `json:"lastSeen"`
+	// Changes counts the state changes within the flap window
+	Changes  int     `json:"changes"`
+	Flapping bool    `json:"flapping"`
+	History  []Entry `json:"history"`
+}
+
+// CheckHistory returns the results of the named check & the history of each
+// of its objects, sorted by key. ok is false when the check hasn't run.
+func (h *History) CheckHistory(name string) (results []Entry, objects []*ObjectHistory, ok bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	checkResults, ok := h.checks[name]
+	if !ok {
+		return nil, nil, false
+	}
+
+	now := time.Now()
+	for key, object := range h.objects[name] {
+		current, _ := object.changes.Last()
+		changes := h.recentChanges(object, now)
+		objects = append(objects, &ObjectHistory{
+			Key:      key,
+			Object:   object.object,
+			Severity: current.Severity,
+			LastSeen: object.lastSeen,
+			Changes:  changes,
+			Flapping: changes > h.flapChanges,
+			History:  object.changes.Items(),
+		})
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+
+	return checkResults.Items(), objects, true
+}
+
+// historyFile is the persisted form of a History.
+type historyFile struct {
+	Checks  map[string][]Entry                   `json:"checks"`
+	Objects map[string]map[string]*ObjectHistory `json:"objects"`
+}
+
+// Save writes the history to file, replacing it atomically.
+func (h *History) Save(file string) error {
+	data := historyFile{
+		Checks:  make(map[string][]Entry),
+		Objects: make(map[string]map[string]*ObjectHistory),
+	}
+
+	h.mu.RLock()
+	for name, results := range h.checks {
+		data.Checks[name] = results.Items()
+		data.Objects[name] = make(map[string]*ObjectHistory)
+		for key, object := range h.objects[name] {
+			data.Objects[name][key] = &ObjectHistory{
+				Object:   object.object,
+				LastSeen: object.lastSeen,
+				History:  object.changes.Items(),
+			}
+		}
+	}
+	h.mu.RUnlock()
+
+	encoded, err := json.Marshal(&data)
+	if err != nil {
+		return fmt.Errorf("error encoding history: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".history-*")
+	if err != nil {
+		return fmt.Errorf("error saving history: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(encoded); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error saving history: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("error saving history: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), file); err != nil {
+		return fmt.Errorf("error saving history: %w", err)
+	}
+	return nil
+}
+
+// Load restores a history saved to file. A missing file is not an error.
+func (h *History) Load(file string) error {
+	encoded, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error reading history: %w", err)
+	}
+
+	var data historyFile
+	if err = json.Unmarshal(encoded, &data); err != nil {
+		return fmt.Errorf("error parsing history %s: %w", file, err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for name, results := range data.Checks {
+		h.checks[name] = newRing[Entry](h.size)
+		for _, entry := range results {
+			h.checks[name].Add(entry)
+		}
+
+		h.objects[name] = make(map[string]*objectHistory)
+		for key, saved := range data.Objects[name] {
+			object := &objectHistory{object: saved.Object, lastSeen: saved.LastSeen, changes: newRing[Entry](h.size)}
+			for _, entry := range saved.History {
+				object.changes.Add(entry)
+			}
+			h.objects[name][key] = object
+		}
+	}
+	return nil
+}
+
+// Persist saves the history to file every interval until ctx is cancelled.
+func (h *History) Persist(ctx context.Context, file string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := h.Save(file); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+		}
+	}
+}
+
+// getHistory returns the recent results of a scheduled check & the state
+// changes of each object it checked. ?object= selects objects by key, e.g.
+// node/service, & ?flapping=true only returns flapping objects.
+func (s *Server) getHistory(c *gin.Context) {
+	var response struct {
+		Check   string           `json:"check"`
+		Results []Entry          `json:"results"`
+		Objects []*ObjectHistory `json:"objects"`
+	}
+
+	name := c.Param("check")
+	if _, ok := s.checks()[name]; !ok {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("unknown check '%s'", name)})
+		return
+	}
+
+	results, objects, ok := s.history.CheckHistory(name)
+	if !ok {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("check '%s' has no history yet", name)})
+		return
+	}
+
+	response.Check = name
+	response.Results = results
+	response.Objects = []*ObjectHistory{}
+	for _, object := range objects {
+		if key := c.Query("object"); key != "" && object.Key != key {
+			continue
+		}
+		if c.Query("flapping") == "true" && !object.Flapping {
+			continue
+		}
+		response.Objects = append(response.Objects, object)
+	}
+
+	c.IndentedJSON(http.StatusOK, response)
+}
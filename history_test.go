@@ -0,0 +1,136 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/glbyers/epimetheus/severity"
+)
+
+var worker = Object{Node: "worker-1"}
+
+// record adds a result of the "nodes" check observing worker, failing or
+// not, & returns its warnings.
+func record(h *History, at time.Time, failing bool) []string {
+	report := &Report{}
+	report.Observe(worker)
+	if failing {
+		report.AddFor(worker, severity.Critical, "Node 'worker-1' is not ready")
+	}
+	result := &Result{Check: "nodes", Time: at, Report: report}
+	h.Record("nodes", result)
+	return result.Warnings
+}
+
+func TestHistoryFlapping(t *testing.T) {
+	start := time.Date(2025, time.March, 3, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		size        int
+		flapChanges int
+		flapWindow  time.Duration
+		every       time.Duration
+		states      []bool
+		want        []bool
+	}{
+		{
+			name: "steady", size: 10, flapChanges: 2, flapWindow: time.Hour, every: time.Minute,
+			states: []bool{true, true, true, true},
+			want:   []bool{false, false, false, false},
+		},
+		{
+			name: "at the threshold", size: 10, flapChanges: 2, flapWindow: time.Hour, every: time.Minute,
+			states: []bool{false, true, false},
+			want:   []bool{false, false, false},
+		},
+		{
+			name: "over the threshold", size: 10, flapChanges: 2, flapWindow: time.Hour, every: time.Minute,
+			states: []bool{false, true, false, true},
+			want:   []bool{false, false, false, true},
+		},
+		{
+			name: "first seen failing", size: 10, flapChanges: 2, flapWindow: time.Hour, every: time.Minute,
+			states: []bool{true, false, true},
+			want:   []bool{false, false, false},
+		},
+		{
+			name: "changes outside the window", size: 10, flapChanges: 2, flapWindow: time.Hour, every: 40 * time.Minute,
+			states: []bool{false, true, false, true},
+			want:   []bool{false, false, false, false},
+		},
+		{
+			name: "full ring not wrapped", size: 3, flapChanges: 2, flapWindow: time.Hour, every: time.Minute,
+			states: []bool{false, true, false},
+			want:   []bool{false, false, false},
+		},
+		{
+			name: "wrapped ring", size: 3, flapChanges: 2, flapWindow: time.Hour, every: time.Minute,
+			states: []bool{false, true, false, true},
+			want:   []bool{false, false, false, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHistory(tt.size, tt.flapChanges, tt.flapWindow, 24*time.Hour)
+			for i, failing := range tt.states {
+				warnings := record(h, start.Add(time.Duration(i)*tt.every), failing)
+				flapping := slices.ContainsFunc(warnings, func(w string) bool {
+					return w == "'worker-1' is flapping, 3 state changes in 1h0m0s"
+				})
+				if flapping != tt.want[i] {
+					t.Errorf("result %d: flapping = %v, want %v (warnings %q)", i, flapping, tt.want[i], warnings)
+				}
+			}
+		})
+	}
+}
+
+func TestHistoryRetention(t *testing.T) {
+	start := time.Now().Add(-3 * time.Hour)
+	h := NewHistory(10, 2, time.Hour, time.Hour)
+
+	record(h, start, true)
+	if _, objects, _ := h.CheckHistory("nodes"); len(objects) != 1 {
+		t.Fatalf("CheckHistory() has %d objects, want 1", len(objects))
+	}
+
+	// worker-1 is gone, another node is checked
+	report := &Report{}
+	report.Observe(Object{Node: "worker-2"})
+	h.Record("nodes", &Result{Check: "nodes", Time: start.Add(2 * time.Hour), Report: report})
+
+	results, objects, ok := h.CheckHistory("nodes")
+	if !ok {
+		t.Fatal("CheckHistory() has no history")
+	}
+	if len(results) != 2 {
+		t.Errorf("CheckHistory() has %d results, want 2", len(results))
+	}
+	if len(objects) != 1 || objects[0].Key != "worker-2" {
+		t.Errorf("CheckHistory() objects = %v, want only worker-2", objects)
+	}
+}
+
+func TestHistoryRecordsChangesOnly(t *testing.T) {
+	start := time.Now()
+	h := NewHistory(10, 2, time.Hour, time.Hour)
+
+	for i, failing := range []bool{false, false, true, true, false} {
+		record(h, start.Add(time.Duration(i)*time.Minute), failing)
+	}
+
+	_, objects, _ := h.CheckHistory("nodes")
+	if len(objects) != 1 {
+		t.Fatalf("CheckHistory() has %d objects, want 1", len(objects))
+	}
+	var got []severity.Level
+	for _, entry := range objects[0].History {
+		got = append(got, entry.Severity)
+	}
+	if want := []severity.Level{severity.OK, severity.Critical, severity.OK}; !slices.Equal(got, want) {
+		t.Errorf("history = %v, want %v", got, want)
+	}
+}
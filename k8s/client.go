@@ -2,20 +2,56 @@ package k8s
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	listersappsv1 "k8s.io/client-go/listers/apps/v1"
+	listersbatchv1 "k8s.io/client-go/listers/batch/v1"
+	listersv1 "k8s.io/client-go/listers/core/v1"
+	listersstoragev1 "k8s.io/client-go/listers/storage/v1"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/util/homedir"
 )
 
+// ErrNotSynced is returned by cache-backed reads until the informers have synced.
+var ErrNotSynced = errors.New("kubernetes cache not synced")
+
 type Client struct {
 	*kubernetes.Clientset
+
+	factory        informers.SharedInformerFactory
+	nodes          listersv1.NodeLister
+	pods           listersv1.PodLister
+	deployments    listersappsv1.DeploymentLister
+	statefulSets   listersappsv1.StatefulSetLister
+	daemonSets     listersappsv1.DaemonSetLister
+	volumeClaims   listersv1.PersistentVolumeClaimLister
+	volumes        listersv1.PersistentVolumeLister
+	storageClasses listersstoragev1.StorageClassLister
+	jobs           listersbatchv1.JobLister
+	cronJobs       listersbatchv1.CronJobLister
+	synced         []cache.InformerSynced
+
+	ready      atomic.Bool
+	lastUpdate atomic.Int64
 }
 
 func New() *Client {
@@ -30,7 +66,7 @@ func ClusterAuth() *Client {
 	if err != nil {
 		panic(err.Error())
 	}
-	return &Client{kubernetes.NewForConfigOrDie(config)}
+	return newClient(kubernetes.NewForConfigOrDie(config))
 }
 
 func LocalAuth() *Client {
@@ -44,71 +80,284 @@ func LocalAuth() *Client {
 	if err != nil {
 		panic(err.Error())
 	}
-	return &Client{kubernetes.NewForConfigOrDie(config)}
+	return newClient(kubernetes.NewForConfigOrDie(config))
+}
+
+func newClient(clientset *kubernetes.Clientset) *Client {
+	c := &Client{
+		Clientset: clientset,
+		factory: informers.NewSharedInformerFactoryWithOptions(clientset, 0,
+			informers.WithTransform(stripManagedFields)),
+	}
+
+	nodes := c.factory.Core().V1().Nodes()
+	pods := c.factory.Core().V1().Pods()
+	deployments := c.factory.Apps().V1().Deployments()
+	statefulSets := c.factory.Apps().V1().StatefulSets()
+	daemonSets := c.factory.Apps().V1().DaemonSets()
+	volumeClaims := c.factory.Core().V1().PersistentVolumeClaims()
+	volumes := c.factory.Core().V1().PersistentVolumes()
+	storageClasses := c.factory.Storage().V1().StorageClasses()
+	jobs := c.factory.Batch().V1().Jobs()
+	cronJobs := c.factory.Batch().V1().CronJobs()
+	c.nodes = nodes.Lister()
+	c.pods = pods.Lister()
+	c.deployments = deployments.Lister()
+	c.statefulSets = statefulSets.Lister()
+	c.daemonSets = daemonSets.Lister()
+	c.volumeClaims = volumeClaims.Lister()
+	c.volumes = volumes.Lister()
+	c.storageClasses = storageClasses.Lister()
+	c.jobs = jobs.Lister()
+	c.cronJobs = cronJobs.Lister()
+
+	for _, informer := range []cache.SharedIndexInformer{
+		nodes.Informer(), pods.Informer(),
+		deployments.Informer(), statefulSets.Informer(), daemonSets.Informer(),
+		volumeClaims.Informer(), volumes.Informer(), storageClasses.Informer(),
+		jobs.Informer(), cronJobs.Informer(),
+	} {
+		//goland:noinspection GoUnhandledErrorResult
+		informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    func(interface{}) { c.touch() },
+			UpdateFunc: func(interface{}, interface{}) { c.touch() },
+			DeleteFunc: func(interface{}) { c.touch() },
+		})
+		c.synced = append(c.synced, informer.HasSynced)
+	}
+
+	return c
+}
+
+// Start runs the informers until ctx is cancelled. Reads are served from the
+// local cache once it has synced, see Ready.
+func (c *Client) Start(ctx context.Context) {
+	c.factory.Start(ctx.Done())
+	go func() {
+		if cache.WaitForCacheSync(ctx.Done(), c.synced...) {
+			c.touch()
+			c.ready.Store(true)
+		}
+	}()
+}
+
+// Ready reports whether the informer caches have synced.
+func (c *Client) Ready() bool {
+	return c.ready.Load()
+}
+
+// LastUpdate returns the time the cache last received an event from the API
+// server. A large gap suggests the watch has stalled & the cache is stale.
+func (c *Client) LastUpdate() time.Time {
+	return time.Unix(0, c.lastUpdate.Load())
+}
+
+// OnNodeChange calls handler whenever a node is added, updated or deleted.
+// old is nil for added nodes & new is nil for deleted ones. Nodes in the
+// cache are added when the handler is registered.
+func (c *Client) OnNodeChange(handler func(old, new *corev1.Node)) {
+	onChange(c.factory.Core().V1().Nodes().Informer(), handler)
+}
+
+// OnPodChange calls handler whenever a pod is added, updated or deleted, see
+// OnNodeChange.
+func (c *Client) OnPodChange(handler func(old, new *corev1.Pod)) {
+	onChange(c.factory.Core().V1().Pods().Informer(), handler)
+}
+
+func onChange[T any](informer cache.SharedIndexInformer, handler func(old, new *T)) {
+	//goland:noinspection GoUnhandledErrorResult
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if o, ok := obj.(*T); ok {
+				handler(nil, o)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			o, ok1 := oldObj.(*T)
+			n, ok2 := newObj.(*T)
+			if ok1 && ok2 {
+				handler(o, n)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if o, ok := obj.(*T); ok {
+				handler(o, nil)
+			}
+		},
+	})
+}
+
+func (c *Client) touch() {
+	c.lastUpdate.Store(time.Now().UnixNano())
 }
 
 func (c *Client) GetNode(name string) (*Node, error) {
-	node, err := c.CoreV1().Nodes().Get(context.Background(), name, metav1.GetOptions{})
+	if !c.Ready() {
+		return nil, ErrNotSynced
+	}
+
+	node, err := c.nodes.Get(name)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, err.Error())
+		fmt.Fprintln(os.Stderr, err.Error())
 		return nil, fmt.Errorf("error getting node: %w", err)
 	}
 	return NewNode(node), nil
 }
 
 func (c *Client) GetNodes() ([]*Node, error) {
-	var nodes []*Node
+	return c.listNodes(labels.Everything())
+}
 
-	nodeList, err := c.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+func (c *Client) GetNodesByRole(role string) ([]*Node, error) {
+	if role == "" {
+		return c.GetNodes()
+	}
+
+	selector, err := labels.Parse("node-role.kubernetes.io/" + role)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, err.Error())
+		return nil, fmt.Errorf("error getting nodes: %w", err)
 	}
-	if nodeList == nil {
-		return nil, fmt.Errorf("error getting nodes")
+	return c.listNodes(selector)
+}
+
+func (c *Client) listNodes(selector labels.Selector) ([]*Node, error) {
+	var nodes []*Node
+
+	if !c.Ready() {
+		return nil, ErrNotSynced
 	}
 
-	for _, node := range nodeList.Items {
-		nodes = append(nodes, NewNode(&node))
+	nodeList, err := c.nodes.List(selector)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return nil, fmt.Errorf("error getting nodes: %w", err)
+	}
+	slices.SortFunc(nodeList, func(a, b *corev1.Node) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	for _, node := range nodeList {
+		nodes = append(nodes, NewNode(node))
 	}
 
-	return nodes, err
+	return nodes, nil
 }
 
-func (c *Client) GetNodesByRole(role string) ([]*Node, error) {
-	if role == "" {
-		return c.GetNodes()
+// GetPods lists pods from the cache. Only the label & field selectors of opts
+// are honoured; the supported fields are metadata.name, metadata.namespace,
+// spec.nodeName & status.phase.
+func (c *Client) GetPods(namespace string, opts metav1.ListOptions) ([]*Pod, error) {
+	var pods []*Pod
+
+	if !c.Ready() {
+		return nil, ErrNotSynced
 	}
 
-	var nodes []*Node
-	nodeList, err := c.CoreV1().Nodes().List(
-		context.Background(), metav1.ListOptions{LabelSelector: "node-role.kubernetes.io/" + role})
+	labelSelector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("error getting pods: %w", err)
+	}
+	fieldSelector, err := fields.ParseSelector(opts.FieldSelector)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, err.Error())
+		return nil, fmt.Errorf("error getting pods: %w", err)
 	}
-	if nodeList == nil {
-		return nil, fmt.Errorf("error getting nodes")
+
+	podList, err := c.pods.Pods(namespace).List(labelSelector)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return nil, fmt.Errorf("error getting pods: %w", err)
 	}
+	slices.SortFunc(podList, func(a, b *corev1.Pod) int {
+		return strings.Compare(a.Namespace+"/"+a.Name, b.Namespace+"/"+b.Name)
+	})
 
-	for _, node := range nodeList.Items {
-		nodes = append(nodes, NewNode(&node))
+	for _, pod := range podList {
+		if !fieldSelector.Matches(fields.Set{
+			"metadata.name":      pod.Name,
+			"metadata.namespace": pod.Namespace,
+			"spec.nodeName":      pod.Spec.NodeName,
+			"status.phase":       string(pod.Status.Phase),
+		}) {
+			continue
+		}
+		pods = append(pods, NewPod(pod))
 	}
 
-	return nodes, err
+	return pods, nil
 }
 
-func (c *Client) GetPods(namespace string, opts metav1.ListOptions) ([]*Pod, error) {
-	var pods []*Pod
+// GetDeployments lists deployments from the cache. Only the label selector of
+// opts is honoured, as with the other workload kinds.
+func (c *Client) GetDeployments(namespace string, opts metav1.ListOptions) ([]*Workload, error) {
+	return listWorkloads(c, opts, func(selector labels.Selector) ([]*appsv1.Deployment, error) {
+		return c.deployments.Deployments(namespace).List(selector)
+	}, NewDeployment)
+}
 
-	podList, err := c.CoreV1().Pods(namespace).List(context.Background(), opts)
+func (c *Client) GetStatefulSets(namespace string, opts metav1.ListOptions) ([]*Workload, error) {
+	return listWorkloads(c, opts, func(selector labels.Selector) ([]*appsv1.StatefulSet, error) {
+		return c.statefulSets.StatefulSets(namespace).List(selector)
+	}, NewStatefulSet)
+}
+
+func (c *Client) GetDaemonSets(namespace string, opts metav1.ListOptions) ([]*Workload, error) {
+	return listWorkloads(c, opts, func(selector labels.Selector) ([]*appsv1.DaemonSet, error) {
+		return c.daemonSets.DaemonSets(namespace).List(selector)
+	}, NewDaemonSet)
+}
+
+// GetJobs lists jobs from the cache. Only the label selector of opts is
+// honoured.
+func (c *Client) GetJobs(namespace string, opts metav1.ListOptions) ([]*Job, error) {
+	return listWorkloads(c, opts, func(selector labels.Selector) ([]*batchv1.Job, error) {
+		return c.jobs.Jobs(namespace).List(selector)
+	}, NewJob)
+}
+
+func (c *Client) GetCronJobs(namespace string, opts metav1.ListOptions) ([]*CronJob, error) {
+	return listWorkloads(c, opts, func(selector labels.Selector) ([]*batchv1.CronJob, error) {
+		return c.cronJobs.CronJobs(namespace).List(selector)
+	}, NewCronJob)
+}
+
+func listWorkloads[T metav1.Object, W any](c *Client, opts metav1.ListOptions,
+	list func(labels.Selector) ([]T, error), newWorkload func(T) W) ([]W, error) {
+	var workloads []W
+
+	if !c.Ready() {
+		return nil, ErrNotSynced
+	}
+
+	selector, err := labels.Parse(opts.LabelSelector)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, err.Error())
+		return nil, fmt.Errorf("error getting workloads: %w", err)
 	}
-	if podList == nil {
-		return nil, fmt.Errorf("error getting pods")
+
+	items, err := list(selector)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return nil, fmt.Errorf("error getting workloads: %w", err)
 	}
-	for _, pod := range podList.Items {
-		pods = append(pods, NewPod(&pod))
+	slices.SortFunc(items, func(a, b T) int {
+		return strings.Compare(a.GetNamespace()+"/"+a.GetName(), b.GetNamespace()+"/"+b.GetName())
+	})
+
+	for _, item := range items {
+		workloads = append(workloads, newWorkload(item))
 	}
 
-	return pods, nil
+	return workloads, nil
+}
+
+// stripManagedFields drops managed fields from cached objects as they are
+// never read & account for a good portion of their size.
+func stripManagedFields(obj interface{}) (interface{}, error) {
+	if accessor, err := meta.Accessor(obj); err == nil {
+		accessor.SetManagedFields(nil)
+	}
+	return obj, nil
 }
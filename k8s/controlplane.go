@@ -0,0 +1,50 @@
+package k8s
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// HealthCheck is one line of the API server's verbose /readyz or /livez
+// output, such as "[-]etcd failed: reason withheld".
+type HealthCheck struct {
+	Name    string `json:"name"`
+	OK      bool   `json:"ok"`
+	Message string `json:"message,omitempty"`
+}
+
+// GetHealthChecks queries an API server health endpoint, /readyz or /livez,
+// returning its individual checks & whether it passed overall.
+func (c *Client) GetHealthChecks(ctx context.Context, endpoint string) ([]HealthCheck, bool, error) {
+	result := c.Discovery().RESTClient().Get().AbsPath(endpoint).Param("verbose", "").Do(ctx)
+
+	// a failing endpoint responds 500 with the checks in the body
+	var status int
+	body, err := result.StatusCode(&status).Raw()
+	if err != nil && status != http.StatusInternalServerError {
+		return nil, false, fmt.Errorf("error querying %s: %w", endpoint, err)
+	}
+
+	var checks []HealthCheck
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		var ok bool
+		switch {
+		case strings.HasPrefix(line, "[+]"):
+			ok = true
+		case strings.HasPrefix(line, "[-]"):
+		default:
+			continue
+		}
+
+		name, message, _ := strings.Cut(line[3:], " ")
+		checks = append(checks, HealthCheck{Name: name, OK: ok, Message: message})
+	}
+
+	return checks, status == http.StatusOK, nil
+}
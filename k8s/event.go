@@ -0,0 +1,124 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+const (
+	// eventPageSize is how many events are listed per request to the API
+	// server
+	eventPageSize = 500
+	// maxEvents caps how many events are read in total, so that a noisy
+	// cluster can't make a single request unbounded
+	maxEvents = 5000
+)
+
+// EventFilter selects Warning events. Unset fields match any event, except
+// Since which drops events last seen before it.
+type EventFilter struct {
+	Namespace string
+	Kind      string
+	Name      string
+	Reason    string
+	Since     time.Time
+}
+
+// Event is a Kubernetes event & the object it is about.
+type Event struct {
+	Kind          string    `json:"kind"`
+	Namespace     string    `json:"namespace,omitempty"`
+	Name          string    `json:"name"`
+	Reason        string    `json:"reason"`
+	Message       string    `json:"message"`
+	Count         int32     `json:"count"`
+	LastTimestamp time.Time `json:"lastTimestamp"`
+}
+
+func NewEvent(e *eventsv1.Event) *Event {
+	event := &Event{
+		Kind:      e.Regarding.Kind,
+		Namespace: e.Regarding.Namespace,
+		Name:      e.Regarding.Name,
+		Reason:    e.Reason,
+		Message:   e.Note,
+		Count:     1,
+	}
+
+	// events recorded through the core API only set the deprecated fields
+	switch {
+	case e.Series != nil:
+		event.Count = e.Series.Count
+		event.LastTimestamp = e.Series.LastObservedTime.Time
+	case !e.DeprecatedLastTimestamp.IsZero():
+		event.LastTimestamp = e.DeprecatedLastTimestamp.Time
+	case !e.EventTime.IsZero():
+		event.LastTimestamp = e.EventTime.Time
+	default:
+		event.LastTimestamp = e.CreationTimestamp.Time
+	}
+	if e.Series == nil && e.DeprecatedCount > 0 {
+		event.Count = e.DeprecatedCount
+	}
+	return event
+}
+
+// Regarding reports whether the event is about the object of kind in
+// namespace with name.
+func (e *Event) Regarding(kind, namespace, name string) bool {
+	return e.Kind == kind && e.Namespace == namespace && e.Name == name
+}
+
+// GetWarningEvents lists the Warning events selected by filter, most recent
+// first. Events come & go too quickly to be worth caching, so they are read
+// from the API server, which does the filtering, a page at a time. truncated
+// is set when there were more than maxEvents events to read, the rest being
+// left out.
+func (c *Client) GetWarningEvents(ctx context.Context, filter EventFilter) (events []*Event, truncated bool, err error) {
+	selector := fields.Set{"type": corev1.EventTypeWarning}
+	if filter.Kind != "" {
+		selector["regarding.kind"] = filter.Kind
+	}
+	if filter.Name != "" {
+		selector["regarding.name"] = filter.Name
+	}
+	if filter.Reason != "" {
+		selector["reason"] = filter.Reason
+	}
+
+	opts := metav1.ListOptions{FieldSelector: selector.AsSelector().String(), Limit: eventPageSize}
+	for read := 0; ; {
+		list, err := c.EventsV1().Events(filter.Namespace).List(ctx, opts)
+		if err != nil {
+			return nil, false, fmt.Errorf("error getting events: %w", err)
+		}
+
+		for i := range list.Items {
+			if event := NewEvent(&list.Items[i]); !event.LastTimestamp.Before(filter.Since) {
+				events = append(events, event)
+			}
+		}
+		read += len(list.Items)
+
+		if list.Continue == "" {
+			break
+		}
+		if read >= maxEvents {
+			truncated = true
+			break
+		}
+		opts.Continue = list.Continue
+	}
+
+	slices.SortFunc(events, func(a, b *Event) int {
+		return b.LastTimestamp.Compare(a.LastTimestamp)
+	})
+	return events, truncated, nil
+}
@@ -0,0 +1,129 @@
+package k8s
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Job is the state of a Job & the CronJob that created it, if any.
+type Job struct {
+	Name            string     `json:"name"`
+	Namespace       string     `json:"namespace"`
+	CronJob         string     `json:"cronJob,omitempty"`
+	Active          int32      `json:"active"`
+	Succeeded       int32      `json:"succeeded"`
+	Failed          int32      `json:"failed"`
+	Started         *time.Time `json:"started,omitempty"`
+	Completed       *time.Time `json:"completed,omitempty"`
+	DeadlineSeconds int64      `json:"activeDeadlineSeconds,omitempty"`
+	// Failure is the reason & message of the Failed condition, if any
+	Failure  string     `json:"failure,omitempty"`
+	FailedAt *time.Time `json:"failedAt,omitempty"`
+}
+
+func NewJob(j *batchv1.Job) *Job {
+	job := &Job{
+		Name:      j.Name,
+		Namespace: j.Namespace,
+		Active:    j.Status.Active,
+		Succeeded: j.Status.Succeeded,
+		Failed:    j.Status.Failed,
+	}
+	for _, ref := range j.OwnerReferences {
+		if ref.Kind == "CronJob" {
+			job.CronJob = ref.Name
+		}
+	}
+	if j.Status.StartTime != nil {
+		job.Started = &j.Status.StartTime.Time
+	}
+	if j.Status.CompletionTime != nil {
+		job.Completed = &j.Status.CompletionTime.Time
+	}
+	if j.Spec.ActiveDeadlineSeconds != nil {
+		job.DeadlineSeconds = *j.Spec.ActiveDeadlineSeconds
+	}
+	for _, cond := range j.Status.Conditions {
+		if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue {
+			job.Failure = fmt.Sprintf("%s: %s", cond.Reason, cond.Message)
+			job.FailedAt = &cond.LastTransitionTime.Time
+		}
+	}
+	return job
+}
+
+// Overdue reports how long an active job has been running past its
+// activeDeadlineSeconds, if at all.
+func (j *Job) Overdue(now time.Time) time.Duration {
+	if j.Active == 0 || j.DeadlineSeconds == 0 || j.Started == nil {
+		return 0
+	}
+	return max(now.Sub(*j.Started)-time.Duration(j.DeadlineSeconds)*time.Second, 0)
+}
+
+// CronJob is the schedule & last runs of a CronJob.
+type CronJob struct {
+	Name           string     `json:"name"`
+	Namespace      string     `json:"namespace"`
+	Schedule       string     `json:"schedule"`
+	TimeZone       string     `json:"timeZone,omitempty"`
+	Suspended      bool       `json:"suspended"`
+	Active         int        `json:"active"`
+	LastSchedule   *time.Time `json:"lastSchedule,omitempty"`
+	LastSuccessful *time.Time `json:"lastSuccessful,omitempty"`
+	Created        time.Time  `json:"created"`
+}
+
+func NewCronJob(cj *batchv1.CronJob) *CronJob {
+	cronJob := &CronJob{
+		Name:      cj.Name,
+		Namespace: cj.Namespace,
+		Schedule:  cj.Spec.Schedule,
+		Suspended: cj.Spec.Suspend != nil && *cj.Spec.Suspend,
+		Active:    len(cj.Status.Active),
+		Created:   cj.CreationTimestamp.Time,
+	}
+	if cj.Spec.TimeZone != nil {
+		cronJob.TimeZone = *cj.Spec.TimeZone
+	}
+	if cj.Status.LastScheduleTime != nil {
+		cronJob.LastSchedule = &cj.Status.LastScheduleTime.Time
+	}
+	if cj.Status.LastSuccessfulTime != nil {
+		cronJob.LastSuccessful = &cj.Status.LastSuccessfulTime.Time
+	}
+	return cronJob
+}
+
+// Interval returns the longest gap between the runs scheduled over the week
+// following from, so that schedules such as weekdays only aren't expected to
+// run every day.
+func (cj *CronJob) Interval(from time.Time) (time.Duration, error) {
+	spec := cj.Schedule
+	if cj.TimeZone != "" {
+		spec = "CRON_TZ=" + cj.TimeZone + " " + spec
+	}
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return 0, fmt.Errorf("CronJob '%s/%s' has an invalid schedule: %w", cj.Namespace, cj.Name, err)
+	}
+
+	var interval time.Duration
+	last := schedule.Next(from)
+	for i := 0; i < 1000 && !last.IsZero(); i++ {
+		next := schedule.Next(last)
+		if next.IsZero() {
+			break
+		}
+		interval = max(interval, next.Sub(last))
+		if next.Sub(from) > 7*24*time.Hour {
+			break
+		}
+		last = next
+	}
+	return interval, nil
+}
@@ -0,0 +1,58 @@
+package k8s
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronJobInterval(t *testing.T) {
+	// a Monday
+	from := time.Date(2025, time.March, 3, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		schedule string
+		timeZone string
+		want     time.Duration
+		wantErr  bool
+	}{
+		{name: "every 5 minutes", schedule: "*/5 * * * *", want: 5 * time.Minute},
+		{name: "hourly", schedule: "0 * * * *", want: time.Hour},
+		{name: "hourly macro", schedule: "@hourly", want: time.Hour},
+		{name: "daily", schedule: "30 2 * * *", want: 24 * time.Hour},
+		{name: "twice a day", schedule: "0 6,9 * * *", want: 21 * time.Hour},
+		{name: "weekdays", schedule: "0 9 * * 1-5", want: 72 * time.Hour},
+		{name: "weekly", schedule: "0 0 * * 0", want: 7 * 24 * time.Hour},
+		{name: "monthly", schedule: "0 0 1 * *", want: 30 * 24 * time.Hour},
+		{name: "weekdays in a time zone", schedule: "0 9 * * 1-5", timeZone: "Europe/London", want: 72 * time.Hour},
+		{name: "invalid", schedule: "not a schedule", wantErr: true},
+		{name: "invalid time zone", schedule: "0 * * * *", timeZone: "Nowhere/Special", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cj := &CronJob{Name: "backup", Namespace: "default", Schedule: tt.schedule, TimeZone: tt.timeZone}
+			got, err := cj.Interval(from)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Interval() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Interval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronJobIntervalDST(t *testing.T) {
+	// the week of the spring forward in London, 30 March 2025
+	from := time.Date(2025, time.March, 27, 12, 0, 0, 0, time.UTC)
+	cj := &CronJob{Schedule: "0 9 * * *", TimeZone: "Europe/London"}
+
+	got, err := cj.Interval(from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 24 * time.Hour; got != want {
+		t.Errorf("Interval() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,71 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Lease is the state of a leader-election lease.
+type Lease struct {
+	Name            string    `json:"name"`
+	Namespace       string    `json:"namespace"`
+	Holder          string    `json:"holder"`
+	Node            string    `json:"node,omitempty"`
+	DurationSeconds int32     `json:"durationSeconds"`
+	RenewTime       time.Time `json:"renewTime"`
+	Transitions     int32     `json:"transitions"`
+
+	hostname string
+}
+
+func NewLease(l *coordinationv1.Lease) *Lease {
+	lease := &Lease{Name: l.Name, Namespace: l.Namespace, hostname: l.Labels["kubernetes.io/hostname"]}
+	if l.Spec.HolderIdentity != nil {
+		lease.Holder = *l.Spec.HolderIdentity
+	}
+	if l.Spec.LeaseDurationSeconds != nil {
+		lease.DurationSeconds = *l.Spec.LeaseDurationSeconds
+	}
+	if l.Spec.RenewTime != nil {
+		lease.RenewTime = l.Spec.RenewTime.Time
+	}
+	if l.Spec.LeaseTransitions != nil {
+		lease.Transitions = *l.Spec.LeaseTransitions
+	}
+	return lease
+}
+
+func (l *Lease) Duration() time.Duration {
+	return time.Duration(l.DurationSeconds) * time.Second
+}
+
+// HolderHost guesses the host of the lease holder from its hostname label,
+// else its identity, as controllers built on client-go identify themselves as
+// hostname_uuid.
+func (l *Lease) HolderHost() string {
+	if l.hostname != "" {
+		return l.hostname
+	}
+	host, _, _ := strings.Cut(l.Holder, "_")
+	return host
+}
+
+// GetLeases lists the leases in namespace. Leases are renewed every few
+// seconds, so they are read from the API server rather than kept in the cache.
+func (c *Client) GetLeases(ctx context.Context, namespace string) ([]*Lease, error) {
+	list, err := c.CoordinationV1().Leases(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting leases: %w", err)
+	}
+
+	var leases []*Lease
+	for i := range list.Items {
+		leases = append(leases, NewLease(&list.Items[i]))
+	}
+	return leases, nil
+}
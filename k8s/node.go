@@ -6,10 +6,16 @@ import (
 	"strings"
 )
 
+// MaintenanceAnnotation marks nodes under planned maintenance. Its value, if
+// any, is given as the reason.
+var MaintenanceAnnotation = "epimetheus.io/maintenance"
+
 type SimpleNode struct {
 	Name    string   `json:"name"`
 	Address string   `json:"address"`
 	Roles   []string `json:"roles"`
+	// Maintenance is why the node is under maintenance, if it is
+	Maintenance string `json:"maintenance,omitempty"`
 }
 
 type Node struct {
@@ -42,6 +48,15 @@ func NewNode(node *corev1.Node) *Node {
 		}
 	}
 
+	if reason, ok := node.Annotations[MaintenanceAnnotation]; ok && MaintenanceAnnotation != "" {
+		n.Maintenance = "annotated"
+		if reason != "" {
+			n.Maintenance += ": " + reason
+		}
+	} else if node.Spec.Unschedulable {
+		n.Maintenance = "cordoned"
+	}
+
 	return &n
 }
 
@@ -56,12 +71,20 @@ func (n *Node) GetAddress(addrType corev1.NodeAddressType) (address string) {
 
 func (n *Node) Status() (status NodeStatus) {
 	status.Node = n
+	for _, cond := range n.FailedConditions() {
+		status.Errors = append(status.Errors, fmt.Sprintf("%v: %s", cond.Type, cond.Message))
+	}
+	return
+}
+
+// FailedConditions returns the conditions that are not in their healthy state.
+func (n *Node) FailedConditions() (conditions []corev1.NodeCondition) {
 	for _, cond := range n.Node.Status.Conditions {
 		// All conditions except NodeReady should be false
 		if cond.Type != corev1.NodeReady && cond.Status != corev1.ConditionFalse {
-			status.Errors = append(status.Errors, fmt.Sprintf("%v: %s", cond.Type, cond.Message))
+			conditions = append(conditions, cond)
 		} else if cond.Type == corev1.NodeReady && cond.Status != corev1.ConditionTrue {
-			status.Errors = append(status.Errors, fmt.Sprintf("%v: %s", cond.Type, cond.Message))
+			conditions = append(conditions, cond)
 		}
 	}
 	return
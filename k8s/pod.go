@@ -8,6 +8,8 @@ type SimplePod struct {
 	Name      string            `json:"name,omitempty"`
 	Namespace string            `json:"namespace,omitempty"`
 	Status    *corev1.PodStatus `json:"status,omitempty"`
+	// Events are the pod's recent Warning events, when asked for
+	Events []*Event `json:"events,omitempty"`
 }
 
 type Pod struct {
@@ -0,0 +1,60 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AccessGroup is the API group of the virtual resources checked by CanGet.
+const AccessGroup = "epimetheus.io"
+
+// ReviewToken validates a bearer token with the TokenReview API, returning
+// the user it belongs to.
+func (c *Client) ReviewToken(ctx context.Context, token string, audiences []string) (*authenticationv1.UserInfo, error) {
+	review, err := c.AuthenticationV1().TokenReviews().Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token, Audiences: audiences},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error reviewing token: %w", err)
+	}
+
+	if !review.Status.Authenticated {
+		if review.Status.Error != "" {
+			return nil, fmt.Errorf("token not authenticated: %s", review.Status.Error)
+		}
+		return nil, fmt.Errorf("token not authenticated")
+	}
+	return &review.Status.User, nil
+}
+
+// CanGet asks the SubjectAccessReview API whether the user may get the
+// virtual resource in AccessGroup. It needn't exist, RBAC rules may grant
+// access to any resource name.
+func (c *Client) CanGet(ctx context.Context, user *authenticationv1.UserInfo, resource string) (bool, error) {
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for key, value := range user.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+
+	review, err := c.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Verb:     "get",
+				Group:    AccessGroup,
+				Resource: resource,
+			},
+			User:   user.Username,
+			Groups: user.Groups,
+			UID:    user.UID,
+			Extra:  extra,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return false, fmt.Errorf("error reviewing access: %w", err)
+	}
+	return review.Status.Allowed, nil
+}
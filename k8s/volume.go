@@ -0,0 +1,244 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// VolumeClaim is a PersistentVolumeClaim & the usage of its volume, when
+// known.
+type VolumeClaim struct {
+	Name         string    `json:"name"`
+	Namespace    string    `json:"namespace"`
+	Phase        string    `json:"phase"`
+	Volume       string    `json:"volume,omitempty"`
+	StorageClass string    `json:"storageClass,omitempty"`
+	Capacity     string    `json:"capacity,omitempty"`
+	Created      time.Time `json:"created"`
+	// ResizePending is set while the volume waits for its file system to be
+	// expanded on the node
+	ResizePending bool `json:"resizePending,omitempty"`
+	// WaitingForConsumer is set on pending claims of a storage class that
+	// binds on first use, while no pod uses them
+	WaitingForConsumer bool         `json:"waitingForConsumer,omitempty"`
+	Usage              *VolumeUsage `json:"usage,omitempty"`
+}
+
+func NewVolumeClaim(pvc *corev1.PersistentVolumeClaim) *VolumeClaim {
+	claim := &VolumeClaim{
+		Name:      pvc.Name,
+		Namespace: pvc.Namespace,
+		Phase:     string(pvc.Status.Phase),
+		Volume:    pvc.Spec.VolumeName,
+		Created:   pvc.CreationTimestamp.Time,
+	}
+	if pvc.Spec.StorageClassName != nil {
+		claim.StorageClass = *pvc.Spec.StorageClassName
+	}
+	if capacity, ok := pvc.Status.Capacity[corev1.ResourceStorage]; ok {
+		claim.Capacity = capacity.String()
+	}
+	for _, cond := range pvc.Status.Conditions {
+		if cond.Type == corev1.PersistentVolumeClaimFileSystemResizePending && cond.Status == corev1.ConditionTrue {
+			claim.ResizePending = true
+		}
+	}
+	return claim
+}
+
+// PersistentVolume is the state of a PersistentVolume & the claim bound to it.
+type PersistentVolume struct {
+	Name         string `json:"name"`
+	Phase        string `json:"phase"`
+	Claim        string `json:"claim,omitempty"`
+	StorageClass string `json:"storageClass,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+	Message      string `json:"message,omitempty"`
+
+	claimNamespace string
+}
+
+func NewPersistentVolume(pv *corev1.PersistentVolume) *PersistentVolume {
+	volume := &PersistentVolume{
+		Name:         pv.Name,
+		Phase:        string(pv.Status.Phase),
+		StorageClass: pv.Spec.StorageClassName,
+		Reason:       pv.Status.Reason,
+		Message:      pv.Status.Message,
+	}
+	if ref := pv.Spec.ClaimRef; ref != nil {
+		volume.Claim = ref.Namespace + "/" + ref.Name
+		volume.claimNamespace = ref.Namespace
+	}
+	return volume
+}
+
+// VolumeUsage is the usage of a mounted volume as reported by the kubelet.
+type VolumeUsage struct {
+	Node           string `json:"node"`
+	UsedBytes      uint64 `json:"usedBytes"`
+	CapacityBytes  uint64 `json:"capacityBytes"`
+	AvailableBytes uint64 `json:"availableBytes"`
+}
+
+// Ratio is the fraction of the volume in use.
+func (u *VolumeUsage) Ratio() float64 {
+	if u.CapacityBytes == 0 {
+		return 0
+	}
+	return float64(u.UsedBytes) / float64(u.CapacityBytes)
+}
+
+// GetVolumeClaims lists the claims in namespace from the cache.
+func (c *Client) GetVolumeClaims(namespace string) ([]*VolumeClaim, error) {
+	var claims []*VolumeClaim
+
+	if !c.Ready() {
+		return nil, ErrNotSynced
+	}
+
+	pvcList, err := c.volumeClaims.PersistentVolumeClaims(namespace).List(labels.Everything())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return nil, fmt.Errorf("error getting volume claims: %w", err)
+	}
+	slices.SortFunc(pvcList, func(a, b *corev1.PersistentVolumeClaim) int {
+		return strings.Compare(a.Namespace+"/"+a.Name, b.Namespace+"/"+b.Name)
+	})
+
+	var used map[string]bool
+	for _, pvc := range pvcList {
+		claim := NewVolumeClaim(pvc)
+		if pvc.Status.Phase == corev1.ClaimPending && c.bindsOnFirstUse(claim.StorageClass) {
+			if used == nil {
+				if used, err = c.usedClaims(namespace); err != nil {
+					return nil, err
+				}
+			}
+			claim.WaitingForConsumer = !used[claim.Namespace+"/"+claim.Name]
+		}
+		claims = append(claims, claim)
+	}
+
+	return claims, nil
+}
+
+// bindsOnFirstUse reports whether claims of the storage class are only bound
+// once a pod using them is scheduled.
+func (c *Client) bindsOnFirstUse(storageClass string) bool {
+	if storageClass == "" {
+		return false
+	}
+	sc, err := c.storageClasses.Get(storageClass)
+	if err != nil {
+		return false
+	}
+	return sc.VolumeBindingMode != nil && *sc.VolumeBindingMode == storagev1.VolumeBindingWaitForFirstConsumer
+}
+
+// usedClaims returns the claims in namespace used by a pod, keyed by
+// namespace/name, including those of generic ephemeral volumes.
+func (c *Client) usedClaims(namespace string) (map[string]bool, error) {
+	pods, err := c.pods.Pods(namespace).List(labels.Everything())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return nil, fmt.Errorf("error getting pods: %w", err)
+	}
+
+	used := make(map[string]bool)
+	for _, pod := range pods {
+		for _, volume := range pod.Spec.Volumes {
+			switch {
+			case volume.PersistentVolumeClaim != nil:
+				used[pod.Namespace+"/"+volume.PersistentVolumeClaim.ClaimName] = true
+			case volume.Ephemeral != nil:
+				used[pod.Namespace+"/"+pod.Name+"-"+volume.Name] = true
+			}
+		}
+	}
+	return used, nil
+}
+
+// GetPersistentVolumes lists the volumes from the cache, only those bound to
+// a claim in namespace when it is set.
+func (c *Client) GetPersistentVolumes(namespace string) ([]*PersistentVolume, error) {
+	var volumes []*PersistentVolume
+
+	if !c.Ready() {
+		return nil, ErrNotSynced
+	}
+
+	pvList, err := c.volumes.List(labels.Everything())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return nil, fmt.Errorf("error getting persistent volumes: %w", err)
+	}
+	slices.SortFunc(pvList, func(a, b *corev1.PersistentVolume) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	for _, pv := range pvList {
+		volume := NewPersistentVolume(pv)
+		if namespace != "" && volume.claimNamespace != namespace {
+			continue
+		}
+		volumes = append(volumes, volume)
+	}
+
+	return volumes, nil
+}
+
+// statsSummary is the part of the kubelet's /stats/summary response
+// describing the volumes of each pod.
+type statsSummary struct {
+	Pods []struct {
+		Volume []struct {
+			UsedBytes      *uint64 `json:"usedBytes"`
+			CapacityBytes  *uint64 `json:"capacityBytes"`
+			AvailableBytes *uint64 `json:"availableBytes"`
+			PVCRef         *struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"pvcRef"`
+		} `json:"volume"`
+	} `json:"pods"`
+}
+
+// GetVolumeUsage asks the kubelet of a node, through the API server, for the
+// usage of the claimed volumes mounted there, keyed by namespace/name.
+func (c *Client) GetVolumeUsage(ctx context.Context, node string) (map[string]*VolumeUsage, error) {
+	body, err := c.CoreV1().RESTClient().Get().
+		AbsPath("/api/v1/nodes", node, "proxy", "stats", "summary").Do(ctx).Raw()
+	if err != nil {
+		return nil, fmt.Errorf("error getting volume stats of node %s: %w", node, err)
+	}
+
+	var summary statsSummary
+	if err = json.Unmarshal(body, &summary); err != nil {
+		return nil, fmt.Errorf("error parsing volume stats of node %s: %w", node, err)
+	}
+
+	usage := make(map[string]*VolumeUsage)
+	for _, pod := range summary.Pods {
+		for _, volume := range pod.Volume {
+			if volume.PVCRef == nil || volume.UsedBytes == nil || volume.CapacityBytes == nil {
+				continue
+			}
+			u := &VolumeUsage{Node: node, UsedBytes: *volume.UsedBytes, CapacityBytes: *volume.CapacityBytes}
+			if volume.AvailableBytes != nil {
+				u.AvailableBytes = *volume.AvailableBytes
+			}
+			usage[volume.PVCRef.Namespace+"/"+volume.PVCRef.Name] = u
+		}
+	}
+	return usage, nil
+}
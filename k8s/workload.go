@@ -0,0 +1,102 @@
+package k8s
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Workload is a common view of Deployments, StatefulSets & DaemonSets.
+type Workload struct {
+	Kind               string `json:"kind"`
+	Name               string `json:"name"`
+	Namespace          string `json:"namespace"`
+	Desired            int32  `json:"desired"`
+	Ready              int32  `json:"ready"`
+	Available          int32  `json:"available"`
+	Updated            int32  `json:"updated"`
+	Generation         int64  `json:"generation"`
+	ObservedGeneration int64  `json:"observedGeneration"`
+
+	conditions []appsv1.DeploymentCondition
+}
+
+func NewDeployment(d *appsv1.Deployment) *Workload {
+	return &Workload{
+		Kind:               "Deployment",
+		Name:               d.Name,
+		Namespace:          d.Namespace,
+		Desired:            replicas(d.Spec.Replicas),
+		Ready:              d.Status.ReadyReplicas,
+		Available:          d.Status.AvailableReplicas,
+		Updated:            d.Status.UpdatedReplicas,
+		Generation:         d.Generation,
+		ObservedGeneration: d.Status.ObservedGeneration,
+		conditions:         d.Status.Conditions,
+	}
+}
+
+func NewStatefulSet(s *appsv1.StatefulSet) *Workload {
+	return &Workload{
+		Kind:               "StatefulSet",
+		Name:               s.Name,
+		Namespace:          s.Namespace,
+		Desired:            replicas(s.Spec.Replicas),
+		Ready:              s.Status.ReadyReplicas,
+		Available:          s.Status.AvailableReplicas,
+		Updated:            s.Status.UpdatedReplicas,
+		Generation:         s.Generation,
+		ObservedGeneration: s.Status.ObservedGeneration,
+	}
+}
+
+func NewDaemonSet(d *appsv1.DaemonSet) *Workload {
+	return &Workload{
+		Kind:               "DaemonSet",
+		Name:               d.Name,
+		Namespace:          d.Namespace,
+		Desired:            d.Status.DesiredNumberScheduled,
+		Ready:              d.Status.NumberReady,
+		Available:          d.Status.NumberAvailable,
+		Updated:            d.Status.UpdatedNumberScheduled,
+		Generation:         d.Generation,
+		ObservedGeneration: d.Status.ObservedGeneration,
+	}
+}
+
+// Errors returns the reasons the workload is considered unhealthy. Having no
+// replicas available or a rollout that exceeded its progress deadline are
+// errors, while being under-replicated or having a spec change the controller
+// has yet to observe only leave it degraded.
+func (w *Workload) Errors() (errors, degraded []string) {
+	if w.Available < w.Desired {
+		msg := fmt.Sprintf("%s '%s/%s' has %d of %d replicas available",
+			w.Kind, w.Namespace, w.Name, w.Available, w.Desired)
+		if w.Available == 0 {
+			errors = append(errors, msg)
+		} else {
+			degraded = append(degraded, msg)
+		}
+	}
+	if w.ObservedGeneration < w.Generation {
+		degraded = append(degraded, fmt.Sprintf("%s '%s/%s' generation %d not observed, controller is at %d",
+			w.Kind, w.Namespace, w.Name, w.Generation, w.ObservedGeneration))
+	}
+	for _, cond := range w.conditions {
+		if cond.Type == appsv1.DeploymentProgressing && cond.Status == corev1.ConditionFalse &&
+			cond.Reason == "ProgressDeadlineExceeded" {
+			errors = append(errors, fmt.Sprintf("%s '%s/%s' rollout stalled: %s",
+				w.Kind, w.Namespace, w.Name, cond.Message))
+		}
+	}
+	return
+}
+
+func replicas(r *int32) int32 {
+	// nil defaults to 1 replica
+	if r == nil {
+		return 1
+	}
+	return *r
+}
@@ -2,38 +2,63 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/glbyers/epimetheus/alertmanager"
+	"github.com/glbyers/epimetheus/auth"
+	"github.com/glbyers/epimetheus/etcd"
+	"github.com/glbyers/epimetheus/events"
 	"github.com/glbyers/epimetheus/k8s"
+	"github.com/glbyers/epimetheus/metrics"
+	"github.com/glbyers/epimetheus/notify"
+	"github.com/glbyers/epimetheus/reload"
+	"github.com/glbyers/epimetheus/rules"
+	"github.com/glbyers/epimetheus/severity"
+	"github.com/glbyers/epimetheus/silence"
 	"github.com/glbyers/epimetheus/talos"
 	"github.com/thanhpk/randstr"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 
 	"fmt"
 	"net/http"
-	"slices"
 	"time"
 
 	"github.com/alecthomas/units"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/siderolabs/talos/pkg/machinery/api/common"
-	"github.com/siderolabs/talos/pkg/machinery/api/machine"
-	"github.com/siderolabs/talos/pkg/machinery/client"
 
-	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type Server struct {
 	k8s   *k8s.Client
 	talos *talos.Client
+	rules *rules.Rules
+	// scheduler is nil when background checks are disabled
+	scheduler *Scheduler
+	history   *History
+	events    *events.Broker
+	silences  *silence.Store
+	stages    *machineStages
 	*gin.Engine
 }
 type Args struct {
-	Listen   string
-	Username string
-	Password string
-	Server   *Server
+	Listen         string
+	Username       string
+	Password       string
+	TLS            *tls.Config
+	Authenticators []auth.Authenticator
+	// AuthSchemes are the WWW-Authenticate schemes of the authenticators
+	AuthSchemes []string
+	Server      *Server
 }
 
 func main() {
@@ -63,38 +88,100 @@ func main() {
 		c.AbortWithStatus(http.StatusInternalServerError)
 	}))
 
-	// Anonymous endpoint for liveness & readiness probes
+	// Anonymous endpoints for liveness & readiness probes
 	s.GET("/ping", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "pong"})
 	})
+	s.GET("/ready", func(c *gin.Context) {
+		if !s.k8s.Ready() {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"message": k8s.ErrNotSynced.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "ready"})
+	})
 
-	// All /v1 endpoints require basic auth
-	v1 := s.Group("/v1", gin.BasicAuth(gin.Accounts{
-		args.Username: args.Password,
-	}))
+	// All /v1 endpoints & metrics require authentication
+	authenticate := auth.Middleware("Authorization Required", args.AuthSchemes, args.Authenticators...)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		metrics.NewCollector(s.k8s, s.talos),
+	)
+	s.GET("/metrics", authenticate, auth.Require("metrics"), s.cacheStatus, gin.WrapH(promhttp.HandlerFor(registry, promhttp.HandlerOpts{})))
+
+	// Routes are split into groups that accounts may be limited to
+	v1 := s.Group("/v1", authenticate, s.cacheStatus)
+	service := auth.Require("service")
+	pod := auth.Require("pod")
+
+	v1.GET("/health", auth.Require("health"), s.getHealth)
+	v1.GET("/history/:check", auth.Require("history"), s.getHistory)
+	v1.GET("/events", auth.Require("events"), s.getEvents)
+	v1.GET("/events/stream", auth.Require("events"), s.getEventStream)
+
+	{
+		silences := v1.Group("/silences")
+		write := auth.Require("silences" + auth.WriteSuffix)
+		silences.GET("", auth.Require("silences"), s.getSilences)
+		silences.POST("", write, s.postSilence)
+		silences.DELETE("/:id", write, s.deleteSilence)
+	}
+
+	v1.GET("/service", service, s.getServiceList)
+	v1.GET("/service/:service", service, s.getService)
 
-	v1.GET("/service", s.getServiceList)
-	v1.GET("/service/:service", s.getService)
+	{
+		etcdGroup := v1.Group("/etcd", auth.Require("etcd"))
+		etcdGroup.GET("/alarms", s.getEtcdAlarms)
+		etcdGroup.GET("/members", s.getEtcdMembers)
+		etcdGroup.GET("/status", s.getEtcdStatus)
+	}
 
-	v1.GET("/etcd/alarms", s.getEtcdAlarms)
-	v1.GET("/etcd/status", s.getEtcdStatus)
+	v1.GET("/controlplane", auth.Require("controlplane"), s.getControlPlane)
+	v1.GET("/lease", auth.Require("leases"), s.getLeases)
+	v1.GET("/lease/:namespace", auth.Require("leases"), s.getLeases)
+	v1.GET("/pvc", auth.Require("pvc"), s.getVolumes)
+	v1.GET("/pvc/:namespace", auth.Require("pvc"), s.getVolumes)
 
-	v1.GET("/pod", s.getPods)
-	v1.GET("/pod/:namespace", s.getPods)
+	{
+		jobs := v1.Group("", auth.Require("job"))
+		jobs.GET("/job", s.getJobs("jobs", s.checkJobs))
+		jobs.GET("/job/:namespace", s.getJobs("jobs", s.checkJobs))
+		jobs.GET("/cronjob", s.getJobs("cronjobs", s.checkCronJobs))
+		jobs.GET("/cronjob/:namespace", s.getJobs("cronjobs", s.checkCronJobs))
+	}
+
+	v1.GET("/pod", pod, s.getPods)
+	v1.GET("/pod/:namespace", pod, s.getPods)
+
+	{
+		workloads := v1.Group("", auth.Require("workload"))
+		workloads.GET("/deployment", s.getWorkloads("deployments", s.k8s.GetDeployments))
+		workloads.GET("/deployment/:namespace", s.getWorkloads("deployments", s.k8s.GetDeployments))
+		workloads.GET("/statefulset", s.getWorkloads("statefulsets", s.k8s.GetStatefulSets))
+		workloads.GET("/statefulset/:namespace", s.getWorkloads("statefulsets", s.k8s.GetStatefulSets))
+		workloads.GET("/daemonset", s.getWorkloads("daemonsets", s.k8s.GetDaemonSets))
+		workloads.GET("/daemonset/:namespace", s.getWorkloads("daemonsets", s.k8s.GetDaemonSets))
+	}
 
-	v1.GET("/images", s.getImages)
-	v1.GET("/time/:server", s.getTimeCheck)
+	v1.GET("/images", auth.Require("images"), s.getImages)
+	v1.GET("/time/:server", auth.Require("time"), s.getTimeCheck)
 
 	{
 		nodes := v1.Group("/node")
-		nodes.GET("", s.getNodes)
-		nodes.GET("/:name", s.getNodeStatus)
-		nodes.GET("/:name/service", s.getServiceList)
-		nodes.GET("/:name/service/:service", s.getService)
-		nodes.GET("/:name/pod", s.getPods)
-		nodes.GET("/:name/pod/:namespace", s.getPods)
-		nodes.GET("/:name/info", s.getNodeSystemInfo)
-		nodes.GET("/:name/metadata", s.getNodeMetadata)
+		node := auth.Require("node")
+		metadata := auth.Require("metadata")
+		nodes.GET("", node, s.getNodes)
+		nodes.GET("/:name", node, s.getNodeStatus)
+		nodes.GET("/:name/service", service, s.getServiceList)
+		nodes.GET("/:name/service/:service", service, s.getService)
+		nodes.GET("/:name/pod", pod, s.getPods)
+		nodes.GET("/:name/pod/:namespace", pod, s.getPods)
+		nodes.GET("/:name/events", auth.Require("events"), s.getNodeEvents)
+		nodes.GET("/:name/info", metadata, s.getNodeSystemInfo)
+		nodes.GET("/:name/metadata", metadata, s.getNodeMetadata)
 	}
 
 	s.NoRoute(func(c *gin.Context) {
@@ -104,7 +191,20 @@ func main() {
 		c.JSON(http.StatusMethodNotAllowed, gin.H{"message": "Method not allowed"})
 	})
 
-	if err := s.Run(args.Listen); err != nil {
+	if args.TLS == nil {
+		if err := s.Run(args.Listen); err != nil {
+			panic(err.Error())
+		}
+		return
+	}
+
+	srv := &http.Server{
+		Addr:      args.Listen,
+		Handler:   s.Handler(),
+		TLSConfig: args.TLS,
+	}
+	// certificates come from TLSConfig.GetCertificate
+	if err := srv.ListenAndServeTLS("", ""); err != nil {
 		panic(err.Error())
 	}
 }
@@ -115,21 +215,96 @@ func setup(ctx context.Context) *Args {
 		Username: getEnv("AUTH_USERNAME", "ghost"),
 		Password: os.Getenv("AUTH_PASSWORD"),
 	}
-
-	if args.Password == "" {
-		args.Password = randstr.String(32)
-		fmt.Printf("WARNING: Using randomly generated credentials: %s:%s\n", args.Username, args.Password)
+	var methods []string
+	for _, method := range strings.Split(getEnv("AUTH_METHODS", "basic"), ",") {
+		methods = append(methods, strings.TrimSpace(method))
 	}
 
 	apidClient, err := talos.New(ctx)
 	if err != nil {
 		panic(err.Error())
 	}
+	k8s.MaintenanceAnnotation = getEnv("MAINTENANCE_ANNOTATION", k8s.MaintenanceAnnotation)
+	k8sClient := k8s.New()
+	// informers live for the lifetime of the process, not the setup context
+	k8sClient.Start(context.Background())
+
+	healthRules := rules.Default()
+	if file, ok := os.LookupEnv("HEALTH_RULES_FILE"); ok {
+		healthRules, err = rules.Load(file)
+		if err != nil {
+			panic(err.Error())
+		}
+	}
+
 	args.Server = &Server{
-		k8s:    k8s.New(),
+		k8s:    k8sClient,
 		talos:  apidClient,
+		rules:  healthRules,
+		events: events.NewBroker(),
+		stages: newMachineStages(),
 		Engine: gin.New(),
 	}
+	args.Server.startWatches(context.Background())
+
+	args.Server.silences, err = silence.NewStore(getEnv("SILENCES_FILE", ""))
+	if err != nil {
+		panic(err.Error())
+	}
+
+	if certFile, ok := os.LookupEnv("TLS_CERT_FILE"); ok {
+		args.TLS, err = setupTLS(certFile, getEnv("TLS_KEY_FILE", ""), getEnv("TLS_MIN_VERSION", "1.2"))
+		if err != nil {
+			panic(err.Error())
+		}
+	}
+
+	for _, method := range methods {
+		switch method {
+		case "basic":
+			if args.Password == "" {
+				args.Password = randstr.String(32)
+				fmt.Printf("WARNING: Using randomly generated credentials: %s:%s\n", args.Username, args.Password)
+			}
+			args.Authenticators = append(args.Authenticators, auth.Basic(gin.Accounts{
+				args.Username: args.Password,
+			}))
+			args.AuthSchemes = appendScheme(args.AuthSchemes, "Basic")
+		case "mtls":
+			authenticator, err := setupClientAuth(args.TLS,
+				getEnv("TLS_CLIENT_CA_FILE", ""), getEnv("TLS_CLIENT_ALLOWLIST_FILE", ""))
+			if err != nil {
+				panic(err.Error())
+			}
+			args.Authenticators = append(args.Authenticators, authenticator)
+		case "htpasswd":
+			users, err := auth.NewHtpasswd(getEnv("AUTH_HTPASSWD_FILE", "/etc/epimetheus/htpasswd"))
+			if err != nil {
+				panic(err.Error())
+			}
+			go reload.Watch(context.Background(), 10*time.Second, users.Files(), users.Load)
+			args.Authenticators = append(args.Authenticators, users.Authenticator())
+			args.AuthSchemes = appendScheme(args.AuthSchemes, "Basic")
+		case "token":
+			ttl, err := time.ParseDuration(getEnv("AUTH_TOKEN_CACHE_TTL", "1m"))
+			if err != nil {
+				panic(fmt.Sprintf("invalid AUTH_TOKEN_CACHE_TTL: %v", err))
+			}
+			var audiences []string
+			if val := getEnv("AUTH_TOKEN_AUDIENCES", ""); val != "" {
+				audiences = strings.Split(val, ",")
+			}
+			review := tokenReviewer(k8sClient, audiences, getEnv("AUTH_TOKEN_RESOURCE", ""))
+			args.Authenticators = append(args.Authenticators, auth.Bearer(review, ttl))
+			args.AuthSchemes = appendScheme(args.AuthSchemes, "Bearer")
+		default:
+			panic(fmt.Sprintf("unknown AUTH_METHODS entry '%s'", method))
+		}
+	}
+
+	if err = setupScheduler(args.Server); err != nil {
+		panic(err.Error())
+	}
 
 	if val, ok := os.LookupEnv("TRUSTED_PROXIES"); ok {
 		err := args.Server.SetTrustedProxies(strings.Split(val, ","))
@@ -145,290 +320,439 @@ func setup(ctx context.Context) *Args {
 	return &args
 }
 
-func (s *Server) getPods(c *gin.Context) {
-	var (
-		node      string
-		namespace string
-		opts      metav1.ListOptions
-		response  struct {
-			Pods   []*k8s.SimplePod `json:"pods"`
-			Errors []string         `json:"errors"`
-		}
-	)
-	node = c.Param("name")
-	namespace = c.Param("namespace")
-	label := c.Query("label")
-	static := c.Query("static") == "true"
-
-	if node != "" {
-		opts.FieldSelector = "spec.nodeName=" + node
+// appendScheme adds an authentication scheme once, as basic & htpasswd share
+// theirs.
+func appendScheme(schemes []string, scheme string) []string {
+	if slices.Contains(schemes, scheme) {
+		return schemes
 	}
+	return append(schemes, scheme)
+}
 
-	if label != "" {
-		opts.LabelSelector = label
+// setupScheduler runs the health checks in the background every
+// CHECK_INTERVAL, overridden per check by CHECK_INTERVALS, e.g.
+// "etcd-status=5m,pods=15s". An interval of 0 disables scheduling, leaving
+// s.scheduler nil when no check is scheduled.
+func setupScheduler(s *Server) error {
+	interval, err := time.ParseDuration(getEnv("CHECK_INTERVAL", "30s"))
+	if err != nil {
+		return fmt.Errorf("invalid CHECK_INTERVAL: %w", err)
+	}
+	timeout, err := time.ParseDuration(getEnv("CHECK_TIMEOUT", "20s"))
+	if err != nil {
+		return fmt.Errorf("invalid CHECK_TIMEOUT: %w", err)
 	}
 
-	podList, err := s.k8s.GetPods(namespace, opts)
+	checks := s.checks()
+	intervals, err := parseIntervals(checks, getEnv("CHECK_INTERVALS", ""))
 	if err != nil {
-		c.IndentedJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
-		return
+		return fmt.Errorf("invalid CHECK_INTERVALS: %w", err)
 	}
 
-	status := http.StatusOK
-	var ok bool
+	s.history, err = setupHistory()
+	if err != nil {
+		return err
+	}
 
-	for _, pod := range podList {
-		ok = false
-		if static {
-			for _, ref := range pod.GetOwnerReferences() {
-				if ref.Kind == "Node" {
-					response.Pods = append(response.Pods, &pod.SimplePod)
-					ok = true
-				}
-			}
-		} else {
-			response.Pods = append(response.Pods, &pod.SimplePod)
-			ok = true
+	scheduler := NewScheduler(checks, interval, intervals, timeout, s.k8s.Ready)
+	if !scheduler.Scheduled() {
+		return nil
+	}
+	s.scheduler = scheduler
+	s.scheduler.OnResult(s.history.Record)
+	s.scheduler.OnResult(s.alarmEvents())
+	if file, ok := os.LookupEnv("NOTIFY_CONFIG_FILE"); ok {
+		config, err := notify.Load(file)
+		if err != nil {
+			return err
 		}
-
-		if ok {
-			for _, cond := range pod.Pod.Status.Conditions {
-				// All conditions except NodeReady should be false
-				if cond.Type == corev1.PodReady && cond.Status != corev1.ConditionTrue && cond.Reason != "PodCompleted" {
-					status = http.StatusExpectationFailed
-					response.Errors = append(response.Errors,
-						fmt.Sprintf("Pod '%s/%s' not ready: %s", pod.Namespace, pod.Name, cond.Message))
-				}
-			}
+		s.scheduler.OnResult(notifyHook(notify.New(config)))
+	}
+	if urls, ok := os.LookupEnv("ALERTMANAGER_URL"); ok {
+		resolveTimeout, err := time.ParseDuration(getEnv("ALERTMANAGER_RESOLVE_TIMEOUT", "5m"))
+		if err != nil {
+			return fmt.Errorf("invalid ALERTMANAGER_RESOLVE_TIMEOUT: %w", err)
 		}
+		client := alertmanager.New(strings.Split(urls, ","), resolveTimeout, 10*time.Second)
+		s.scheduler.OnResult(alertHook(client))
 	}
+	s.scheduler.Start(context.Background())
+	return nil
+}
 
-	c.IndentedJSON(status, response)
+// notifyHook passes the severity of every scheduled result to the notifier,
+// which tells the webhooks about changes.
+func notifyHook(notifier *notify.Notifier) func(name string, result *Result) {
+	return func(name string, result *Result) {
+		notifier.Update(context.Background(), notify.Notification{
+			Check:    name,
+			Severity: result.Severity,
+			Errors:   result.Errors,
+			Warnings: result.Warnings,
+			Time:     result.Time,
+		})
+	}
 }
 
-func (s *Server) getServiceList(c *gin.Context) {
+// alertHook fires an alert in Alertmanager for every object with problems in
+// a scheduled result, one per severity, & resolves the alerts of objects
+// that recovered. Updates are sent in order by one goroutine per check.
+func alertHook(client *alertmanager.Client) func(name string, result *Result) {
+	type update struct {
+		alerts  []alertmanager.Alert
+		partial bool
+	}
+
 	var (
-		name     string
-		nodes    []string
-		response struct {
-			Services []*client.ServiceInfo `json:"services"`
-			Errors   []string              `json:"errors"`
-		}
+		mu     sync.Mutex
+		queues = make(map[string]chan update)
 	)
+	queue := func(name string) chan update {
+		mu.Lock()
+		defer mu.Unlock()
+
+		ch, ok := queues[name]
+		if !ok {
+			ch = make(chan update, 1)
+			queues[name] = ch
+			go func() {
+				for u := range ch {
+					client.Update(context.Background(), name, u.alerts, u.partial)
+				}
+			}()
+		}
+		return ch
+	}
 
-	name = c.Param("name")
-	if name != "" {
-		node, err := s.k8s.GetNode(name)
-		if err != nil {
-			c.IndentedJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
-			return
+	return func(name string, result *Result) {
+		type key struct {
+			Object
+			severity.Level
 		}
-		nodes = append(nodes, node.Address)
-	} else {
-		nodeList, err := s.k8s.GetNodes()
-		if err != nil {
-			if nodeList == nil {
-				c.IndentedJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
-				return
+
+		var (
+			keys    []key
+			grouped = make(map[key][]string)
+			// problems about the check as a whole, such as nodes that didn't
+			// respond, mean objects may be missing rather than healthy
+			partial = result.Partial
+		)
+		for _, problem := range result.Problems {
+			k := key{problem.Object, problem.Severity}
+			if _, ok := grouped[k]; !ok {
+				keys = append(keys, k)
+			}
+			grouped[k] = append(grouped[k], problem.Message)
+			if problem.Object == (Object{}) && problem.Severity == severity.Critical {
+				partial = true
 			}
-			response.Errors = append(response.Errors, err.Error())
 		}
 
-		for _, node := range nodeList {
-			nodes = append(nodes, node.Address)
+		alerts := make([]alertmanager.Alert, 0, len(keys))
+		for _, k := range keys {
+			labels := map[string]string{
+				"alertname": "EpimetheusCheckFailed",
+				"check":     name,
+				"severity":  k.Level.String(),
+			}
+			for label, value := range map[string]string{
+				"node": k.Node, "service": k.Service, "namespace": k.Namespace,
+			} {
+				if value != "" {
+					labels[label] = value
+				}
+			}
+			if k.Name != "" && name == "pods" {
+				labels["pod"] = k.Name
+			} else if k.Name != "" {
+				labels["name"] = k.Name
+			}
+
+			messages := grouped[k]
+			alerts = append(alerts, alertmanager.Alert{
+				Labels: labels,
+				Annotations: map[string]string{
+					"summary":     messages[0],
+					"description": strings.Join(messages, "\n"),
+				},
+			})
 		}
-	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+		// a pending update is superseded, each holds every alert of the check
+		ch := queue(name)
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- update{alerts: alerts, partial: partial}
+	}
+}
 
-	serviceList, err := s.talos.GetServiceList(ctx, nodes)
+// setupHistory keeps HISTORY_SIZE results of each scheduled check & state
+// changes of each object, persisted to HISTORY_FILE every minute when set.
+func setupHistory() (*History, error) {
+	size, err := strconv.Atoi(getEnv("HISTORY_SIZE", "100"))
+	if err != nil || size < 1 {
+		return nil, fmt.Errorf("invalid HISTORY_SIZE '%s'", getEnv("HISTORY_SIZE", "100"))
+	}
+	flapChanges, err := strconv.Atoi(getEnv("HISTORY_FLAP_CHANGES", "4"))
 	if err != nil {
-		if serviceList == nil {
-			c.IndentedJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
-			return
-		}
-		response.Errors = append(response.Errors, err.Error())
-	}
-
-	status := http.StatusOK
-	// Services that never report healthy, but do report running. Maybe we
-	// exclude all extension services?
-	healthUnknown := []string{"dashboard", "ext-iscsid", "ext-qemu-guest-agent", "ext-lldpd"}
-	for _, nodeService := range serviceList {
-		for _, svc := range nodeService.Services {
-			response.Services = append(response.Services, &client.ServiceInfo{
-				Metadata: nodeService.Metadata,
-				Service:  svc,
-			})
-			// dashboard service never reports healthy, but should report running.
-			if !slices.Contains(healthUnknown, svc.Id) && !svc.Health.Healthy {
-				status = http.StatusExpectationFailed
-				response.Errors = append(
-					response.Errors,
-					fmt.Sprintf("Service '%s' on %s not healthy", svc.Id, nodeService.Metadata.Hostname))
-			} else if svc.State != "Running" {
-				status = http.StatusExpectationFailed
-				response.Errors = append(
-					response.Errors,
-					fmt.Sprintf("Service '%s' on %s not running", svc.Id, nodeService.Metadata.Hostname))
-			}
-		}
+		return nil, fmt.Errorf("invalid HISTORY_FLAP_CHANGES: %w", err)
+	}
+	flapWindow, err := time.ParseDuration(getEnv("HISTORY_FLAP_WINDOW", "30m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid HISTORY_FLAP_WINDOW: %w", err)
+	}
+	retention, err := time.ParseDuration(getEnv("HISTORY_RETENTION", "24h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid HISTORY_RETENTION: %w", err)
 	}
 
-	c.IndentedJSON(status, response)
+	history := NewHistory(size, flapChanges, flapWindow, retention)
+	if file, ok := os.LookupEnv("HISTORY_FILE"); ok {
+		if err = history.Load(file); err != nil {
+			return nil, err
+		}
+		go history.Persist(context.Background(), file, time.Minute)
+	}
+	return history, nil
 }
 
-func (s *Server) getService(c *gin.Context) {
-	var (
-		name     string
-		service  string
-		nodes    []string
-		response struct {
-			Service []client.ServiceInfo `json:"service"`
-			Errors  []string             `json:"errors"`
-		}
-	)
+// setupTLS loads the serving certificate & reloads it whenever the files
+// change, so that rotated certificates are picked up without a restart.
+func setupTLS(certFile, keyFile, minVersion string) (*tls.Config, error) {
+	versions := map[string]uint16{
+		"1.2": tls.VersionTLS12,
+		"1.3": tls.VersionTLS13,
+	}
+	version, ok := versions[minVersion]
+	if !ok {
+		return nil, fmt.Errorf("unsupported TLS_MIN_VERSION '%s', use 1.2 or 1.3", minVersion)
+	}
 
-	name = c.Param("name")
-	service = c.Param("service")
+	cert, err := reload.NewCertificate(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	go reload.Watch(context.Background(), 10*time.Second, cert.Files(), cert.Load)
 
-	if name != "" {
-		node, err := s.k8s.GetNode(name)
-		if err != nil {
-			c.IndentedJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
-			return
-		}
-		nodes = append(nodes, node.Address)
-	} else {
-		nodeList, err := s.k8s.GetNodes()
-		if err != nil {
-			c.IndentedJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
-			return
-		}
+	return &tls.Config{
+		MinVersion:     version,
+		GetCertificate: cert.GetCertificate,
+	}, nil
+}
 
-		for _, node := range nodeList {
-			nodes = append(nodes, node.Address)
-		}
+// setupClientAuth asks TLS clients for a certificate signed by the CA. The
+// certificate is optional at the TLS layer so that probes can still reach
+// /ping, but required by the returned authenticator.
+func setupClientAuth(tlsConfig *tls.Config, caFile, allowlistFile string) (auth.Authenticator, error) {
+	if tlsConfig == nil {
+		return nil, fmt.Errorf("mtls authentication requires TLS_CERT_FILE")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	ca, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading client CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
 
-	services, err := s.talos.GetServiceInfo(ctx, nodes, service)
+	clients, err := auth.LoadClients(allowlistFile)
 	if err != nil {
-		if services == nil {
-			c.IndentedJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
-			return
-		}
-		response.Errors = append(response.Errors, err.Error())
-	}
-
-	status := http.StatusOK
-	response.Service = services
-	healthUnknown := []string{"dashboard", "ext-iscsid", "ext-qemu-guest-agent"}
-	for _, svc := range services {
-		// dashboard service never reports healthy, but should report running.
-		if !slices.Contains(healthUnknown, svc.Service.Id) && !svc.Service.Health.Healthy {
-			status = http.StatusExpectationFailed
-			response.Errors = append(
-				response.Errors,
-				fmt.Sprintf("Service '%s' on %s not healthy", svc.Service.Id, svc.Metadata.Hostname))
-		} else if svc.Service.State != "Running" {
-			status = http.StatusExpectationFailed
-			response.Errors = append(
-				response.Errors,
-				fmt.Sprintf("Service '%s' on %s not running", svc.Service.Id, svc.Metadata.Hostname))
-		}
+		return nil, err
 	}
 
-	c.IndentedJSON(status, response)
+	tlsConfig.ClientCAs = pool
+	tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+
+	return auth.ClientCert(clients), nil
 }
 
-func (s *Server) getEtcdStatus(c *gin.Context) {
-	var (
-		leader         uint64
-		nodes          []string
-		etcdStatusList []*machine.EtcdStatus
-		minDbSize      units.Base2Bytes
-		response       struct {
-			Status []*machine.EtcdStatus `json:"status"`
-			Errors []string              `json:"errors"`
+// tokenReviewer validates ServiceAccount & other bearer tokens with the API
+// server. When resource is set the token's user must also be allowed to get
+// that virtual resource in the epimetheus.io API group.
+func tokenReviewer(client *k8s.Client, audiences []string, resource string) auth.TokenReviewer {
+	return func(ctx context.Context, token string) (*auth.Identity, error) {
+		user, err := client.ReviewToken(ctx, token, audiences)
+		if err != nil {
+			return nil, err
 		}
-	)
 
-	minDbSize, _ = units.ParseBase2Bytes(c.DefaultQuery("minDbSize", "512MiB"))
+		if resource != "" {
+			allowed, err := client.CanGet(ctx, user, resource)
+			if err != nil {
+				return nil, err
+			}
+			if !allowed {
+				return nil, fmt.Errorf("%s may not get %s.%s", user.Username, resource, k8s.AccessGroup)
+			}
+		}
 
-	// fetch internal address for control plane nodes
-	nodeList, err := s.k8s.GetNodesByRole("control-plane")
-	if err != nil {
-		c.IndentedJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
-		return
+		return &auth.Identity{Name: user.Username}, nil
 	}
+}
 
-	for _, node := range nodeList {
-		nodes = append(nodes, node.Address)
+// cacheStatus tells clients whether responses come from a synced cache & how
+// long ago that cache last heard from the API server.
+func (s *Server) cacheStatus(c *gin.Context) {
+	c.Header("X-Cache-Synced", strconv.FormatBool(s.k8s.Ready()))
+	if s.k8s.Ready() {
+		age := time.Since(s.k8s.LastUpdate()).Truncate(time.Second)
+		c.Header("X-Cache-Age", strconv.Itoa(int(age.Seconds())))
 	}
+	c.Next()
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// getPods accepts ?events=true to include the recent Warning events of pods
+// that are not ready.
+func (s *Server) getPods(c *gin.Context) {
+	node, namespace, label, static := c.Param("name"), c.Param("namespace"), c.Query("label"), c.Query("static") == "true"
+	events := c.Query("events") == "true"
+	check := func(ctx context.Context) *Result {
+		return s.checkPods(ctx, node, namespace, label, static, events)
+	}
 
-	etcdStatusList, err = s.talos.GetEtcdStatus(ctx, nodes)
-	if err != nil {
-		c.IndentedJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	// only the unfiltered check is scheduled
+	if node == "" && namespace == "" && label == "" && !static && !events {
+		s.respond(c, s.cached(c, "pods", check))
 		return
 	}
+	s.respond(c, check(c.Request.Context()))
+}
+
+// getWorkloads returns a handler listing workloads of one kind, optionally
+// filtered by namespace & label, failing when any are not fully rolled out.
+func (s *Server) getWorkloads(kind string, list func(string, metav1.ListOptions) ([]*k8s.Workload, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		s.respond(c, s.checkWorkloads(c.Request.Context(), kind, list, c.Param("namespace"), c.Query("label")))
+	}
+}
 
-	status := http.StatusOK
-	for _, etcdStatus := range etcdStatusList {
-		response.Status = append(response.Status, etcdStatus)
-		if leader == 0 {
-			leader = etcdStatus.MemberStatus.Leader
-		} else if leader != etcdStatus.MemberStatus.Leader {
-			status = http.StatusExpectationFailed
-			response.Errors = append(response.Errors, "Members don't agree on the same leader")
+// getJobs returns a handler for the named job check, optionally filtered by
+// namespace & label.
+func (s *Server) getJobs(name string, check func(context.Context, string, string) *Result) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		namespace, label := c.Param("namespace"), c.Query("label")
+		run := func(ctx context.Context) *Result {
+			return check(ctx, namespace, label)
 		}
 
-		// database fragmentation checks
-		if etcdStatus.MemberStatus.DbSize > int64(minDbSize) {
-			if float64(etcdStatus.MemberStatus.DbSizeInUse/etcdStatus.MemberStatus.DbSize) > 0.5 {
-				status = http.StatusExpectationFailed
-				response.Errors = append(response.Errors, "db exceeds 50%% fragmentation")
-			}
+		if namespace == "" && label == "" {
+			s.respond(c, s.cached(c, name, run))
+			return
 		}
+		s.respond(c, run(c.Request.Context()))
 	}
-	c.IndentedJSON(status, response)
 }
 
-func (s *Server) getEtcdAlarms(c *gin.Context) {
-	var nodes []string
-	var alarms []*machine.EtcdMemberAlarm
+func (s *Server) getServiceList(c *gin.Context) {
+	name := c.Param("name")
+	check := func(ctx context.Context) *Result {
+		return s.checkServiceList(ctx, name)
+	}
 
-	// fetch internal address for control plane nodes
-	nodeList, err := s.k8s.GetNodesByRole("control-plane")
+	if name == "" {
+		s.respond(c, s.cached(c, "services", check))
+		return
+	}
+	s.respond(c, check(c.Request.Context()))
+}
+
+func (s *Server) getService(c *gin.Context) {
+	s.respond(c, s.checkService(c.Request.Context(), c.Param("name"), c.Param("service")))
+}
+
+// getEtcdStatus accepts ?minDbSize=, ?quota= (e.g. 8GiB), ?maxFragmentation=
+// (a ratio, e.g. 0.5) & ?maxRaftLag= to override the default thresholds.
+func (s *Server) getEtcdStatus(c *gin.Context) {
+	var err error
+
+	thresholds := etcd.DefaultThresholds()
+	if val, ok := c.GetQuery("minDbSize"); ok && err == nil {
+		thresholds.MinDbSize, err = units.ParseBase2Bytes(val)
+	}
+	if val, ok := c.GetQuery("quota"); ok && err == nil {
+		thresholds.Quota, err = units.ParseBase2Bytes(val)
+	}
+	if val, ok := c.GetQuery("maxFragmentation"); ok && err == nil {
+		thresholds.MaxFragmentation, err = strconv.ParseFloat(val, 64)
+	}
+	if val, ok := c.GetQuery("maxRaftLag"); ok && err == nil {
+		thresholds.MaxRaftLag, err = strconv.ParseUint(val, 10, 64)
+	}
+	// negated so NaN is rejected too
+	if err == nil && !(thresholds.MaxFragmentation >= 0 && thresholds.MaxFragmentation <= 1) {
+		err = fmt.Errorf("maxFragmentation must be between 0 & 1, got %v", c.Query("maxFragmentation"))
+	}
 	if err != nil {
-		c.IndentedJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	check := func(ctx context.Context) *Result {
+		return s.checkEtcdStatus(ctx, thresholds)
+	}
 
-	for _, node := range nodeList {
-		nodes = append(nodes, node.Address)
+	if thresholds == etcd.DefaultThresholds() {
+		s.respond(c, s.cached(c, "etcd-status", check))
+		return
 	}
+	s.respond(c, check(c.Request.Context()))
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+func (s *Server) getEtcdAlarms(c *gin.Context) {
+	s.respond(c, s.cached(c, "etcd-alarms", s.checkEtcdAlarms))
+}
 
-	alarms, err = s.talos.GetEtcdAlarms(ctx, nodes)
-	if err != nil {
-		c.IndentedJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+func (s *Server) getEtcdMembers(c *gin.Context) {
+	s.respond(c, s.cached(c, "etcd-members", s.checkEtcdMembers))
+}
+
+func (s *Server) getControlPlane(c *gin.Context) {
+	s.respond(c, s.cached(c, "controlplane", s.checkControlPlane))
+}
+
+func (s *Server) getLeases(c *gin.Context) {
+	namespace := c.Param("namespace")
+	check := func(ctx context.Context) *Result {
+		return s.checkLeases(ctx, namespace)
+	}
+
+	if namespace == "" {
+		s.respond(c, s.cached(c, "leases", check))
 		return
 	}
+	s.respond(c, check(c.Request.Context()))
+}
+
+// getVolumes accepts ?maxUsage= (a ratio, e.g. 0.9) to override the volume
+// usage threshold of the rules.
+func (s *Server) getVolumes(c *gin.Context) {
+	namespace := c.Param("namespace")
+	maxUsage := s.rules.Volumes.MaxUsage
+	val, override := c.GetQuery("maxUsage")
+	if override {
+		var err error
+		if maxUsage, err = strconv.ParseFloat(val, 64); err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		// negated so NaN is rejected too
+		if !(maxUsage >= 0 && maxUsage <= 1) {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("maxUsage must be between 0 & 1, got %v", val)})
+			return
+		}
+	}
+	check := func(ctx context.Context) *Result {
+		return s.checkVolumes(ctx, namespace, maxUsage)
+	}
 
-	if alarms != nil {
-		c.IndentedJSON(http.StatusExpectationFailed, alarms)
+	if namespace == "" && !override {
+		s.respond(c, s.cached(c, "pvcs", check))
 		return
 	}
-	c.IndentedJSON(http.StatusOK, gin.H{"message": "No alarms present"})
+	s.respond(c, check(c.Request.Context()))
 }
 
 func (s *Server) getNodes(c *gin.Context) {
@@ -439,34 +763,20 @@ func (s *Server) getNodes(c *gin.Context) {
 	nodeList, err := s.k8s.GetNodesByRole(role)
 	if err != nil {
 		c.IndentedJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
-	} else {
-		for _, node := range nodeList {
-			nodes = append(nodes, &node.SimpleNode)
-		}
+		return
+	}
+
+	for _, node := range nodeList {
+		nodes = append(nodes, &node.SimpleNode)
 	}
 
 	c.IndentedJSON(http.StatusOK, nodes)
 }
 
+// getNodeStatus accepts ?events=true to include the node's recent Warning
+// events when it is failing.
 func (s *Server) getNodeStatus(c *gin.Context) {
-	var (
-		status     int
-		nodeStatus k8s.NodeStatus
-	)
-
-	node, err := s.k8s.GetNode(c.Param("name"))
-	if err != nil {
-		c.IndentedJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
-		return
-	}
-
-	nodeStatus = node.Status()
-	if nodeStatus.Errors != nil {
-		status = http.StatusExpectationFailed
-	} else {
-		status = http.StatusOK
-	}
-	c.IndentedJSON(status, nodeStatus)
+	s.respond(c, s.checkNodeStatus(c.Request.Context(), c.Param("name"), c.Query("events") == "true"))
 }
 
 func (s *Server) getImages(c *gin.Context) {
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/siderolabs/talos/pkg/machinery/resources/runtime"
+)
+
+// maintenanceStages are the Talos machine stages of nodes undergoing planned
+// work, including booting for nodes coming back from it.
+var maintenanceStages = []runtime.MachineStage{
+	runtime.MachineStageUpgrading,
+	runtime.MachineStageRebooting,
+	runtime.MachineStageShuttingDown,
+	runtime.MachineStageBooting,
+}
+
+// maintenanceChecks are the checks whose problems about a node in
+// maintenance are expected.
+var maintenanceChecks = []string{"nodes", "node", "services", "service", "pods", "controlplane", "leases"}
+
+// machineStages holds the Talos machine stage of each node, by name, as
+// watched through the Talos API.
+type machineStages struct {
+	mu     sync.RWMutex
+	stages map[string]runtime.MachineStage
+}
+
+func newMachineStages() *machineStages {
+	return &machineStages{stages: make(map[string]runtime.MachineStage)}
+}
+
+func (m *machineStages) set(node string, stage runtime.MachineStage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stages[node] = stage
+}
+
+func (m *machineStages) forget(node string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.stages, node)
+}
+
+func (m *machineStages) get(node string) (stage runtime.MachineStage, ok bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	stage, ok = m.stages[node]
+	return stage, ok
+}
+
+// maintenance returns why each node under maintenance is, keyed by both its
+// hostname & Kubernetes name: it is cordoned, carries the maintenance
+// annotation or Talos is upgrading, rebooting, shutting down or booting it.
+func (s *Server) maintenance() map[string]string {
+	reasons := make(map[string]string)
+
+	nodeList, err := s.k8s.GetNodes()
+	if err != nil {
+		return reasons
+	}
+
+	for _, node := range nodeList {
+		reason := node.Maintenance
+		if stage, ok := s.stages.get(node.Name); ok && slices.Contains(maintenanceStages, stage) {
+			reason = "talos " + stage.String()
+		}
+		if reason != "" {
+			reasons[node.Name] = reason
+			reasons[node.Node.Name] = reason
+		}
+	}
+	return reasons
+}
+
+// suppressMaintenance moves problems about nodes under maintenance out of
+// the report's errors & warnings.
+func (s *Server) suppressMaintenance(check string, report *Report) {
+	if !slices.Contains(maintenanceChecks, check) || len(report.Problems) == 0 {
+		return
+	}
+
+	reasons := s.maintenance()
+	if len(reasons) == 0 {
+		return
+	}
+	report.suppress(&report.Maintenance, func(problem Problem) string {
+		if reason, ok := reasons[problem.Node]; ok && problem.Node != "" {
+			return "node in maintenance, " + reason
+		}
+		return ""
+	})
+}
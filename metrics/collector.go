@@ -0,0 +1,247 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/glbyers/epimetheus/k8s"
+	"github.com/glbyers/epimetheus/talos"
+	"github.com/prometheus/client_golang/prometheus"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const namespace = "epimetheus"
+
+var (
+	upDesc = prometheus.NewDesc(
+		namespace+"_up",
+		"Whether the last query of a source succeeded (1) or not (0).",
+		[]string{"source"}, nil)
+
+	nodeConditionDesc = prometheus.NewDesc(
+		namespace+"_node_condition",
+		"The condition of a node, one series per status.",
+		[]string{"node", "condition", "status"}, nil)
+	nodeHealthyDesc = prometheus.NewDesc(
+		namespace+"_node_healthy",
+		"Whether the node is Ready with all failure conditions False.",
+		[]string{"node"}, nil)
+	nodeMaintenanceDesc = prometheus.NewDesc(
+		namespace+"_node_maintenance",
+		"Whether the node is cordoned or carries the maintenance annotation.",
+		[]string{"node"}, nil)
+
+	serviceHealthyDesc = prometheus.NewDesc(
+		namespace+"_talos_service_healthy",
+		"Whether a Talos service reports healthy.",
+		[]string{"node", "service"}, nil)
+	serviceHealthUnknownDesc = prometheus.NewDesc(
+		namespace+"_talos_service_health_unknown",
+		"Whether a Talos service has no health check.",
+		[]string{"node", "service"}, nil)
+	serviceStateDesc = prometheus.NewDesc(
+		namespace+"_talos_service_state",
+		"The state of a Talos service, one series for the current state.",
+		[]string{"node", "service", "state"}, nil)
+
+	etcdLeaderDesc = prometheus.NewDesc(
+		namespace+"_etcd_member_is_leader",
+		"Whether the etcd member is the current leader.",
+		[]string{"node", "member"}, nil)
+	etcdHasLeaderDesc = prometheus.NewDesc(
+		namespace+"_etcd_member_has_leader",
+		"Whether the etcd member knows of a leader.",
+		[]string{"node", "member"}, nil)
+	etcdLearnerDesc = prometheus.NewDesc(
+		namespace+"_etcd_member_is_learner",
+		"Whether the etcd member is a learner.",
+		[]string{"node", "member"}, nil)
+	etcdDbSizeDesc = prometheus.NewDesc(
+		namespace+"_etcd_db_size_bytes",
+		"Size of the etcd database in bytes.",
+		[]string{"node", "member"}, nil)
+	etcdDbSizeInUseDesc = prometheus.NewDesc(
+		namespace+"_etcd_db_size_in_use_bytes",
+		"Size of the etcd database in use in bytes.",
+		[]string{"node", "member"}, nil)
+	etcdRaftIndexDesc = prometheus.NewDesc(
+		namespace+"_etcd_raft_index",
+		"Current raft index of the etcd member.",
+		[]string{"node", "member"}, nil)
+
+	etcdAlarmDesc = prometheus.NewDesc(
+		namespace+"_etcd_alarm",
+		"An active etcd alarm.",
+		[]string{"member", "alarm"}, nil)
+	etcdAlarmsDesc = prometheus.NewDesc(
+		namespace+"_etcd_alarms",
+		"Number of active etcd alarms.",
+		nil, nil)
+)
+
+// Collector queries Kubernetes & Talos on every scrape and exports the
+// results as gauges.
+type Collector struct {
+	k8s     *k8s.Client
+	talos   *talos.Client
+	timeout time.Duration
+}
+
+func NewCollector(k8sClient *k8s.Client, talosClient *talos.Client) *Collector {
+	return &Collector{
+		k8s:     k8sClient,
+		talos:   talosClient,
+		timeout: 10 * time.Second,
+	}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- upDesc
+	ch <- nodeConditionDesc
+	ch <- nodeHealthyDesc
+	ch <- nodeMaintenanceDesc
+	ch <- serviceHealthyDesc
+	ch <- serviceHealthUnknownDesc
+	ch <- serviceStateDesc
+	ch <- etcdLeaderDesc
+	ch <- etcdHasLeaderDesc
+	ch <- etcdLearnerDesc
+	ch <- etcdDbSizeDesc
+	ch <- etcdDbSizeInUseDesc
+	ch <- etcdRaftIndexDesc
+	ch <- etcdAlarmDesc
+	ch <- etcdAlarmsDesc
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	nodeList, err := c.k8s.GetNodes()
+	ch <- up("kubernetes", err == nil)
+	if nodeList == nil {
+		return
+	}
+
+	var (
+		nodes         []string
+		controlPlanes []string
+	)
+	// Talos reports the address it was queried with, so map it back to a name.
+	names := make(map[string]string)
+	for _, node := range nodeList {
+		c.collectNode(ch, node)
+
+		names[node.Address] = node.Name
+		nodes = append(nodes, node.Address)
+		for _, role := range node.Roles {
+			if role == "control-plane" {
+				controlPlanes = append(controlPlanes, node.Address)
+			}
+		}
+	}
+
+	c.collectServices(ctx, ch, nodes, names)
+
+	if len(controlPlanes) > 0 {
+		c.collectEtcdStatus(ctx, ch, controlPlanes, names)
+		c.collectEtcdAlarms(ctx, ch, controlPlanes)
+	}
+}
+
+func (c *Collector) collectNode(ch chan<- prometheus.Metric, node *k8s.Node) {
+	for _, cond := range node.Node.Status.Conditions {
+		for _, status := range []corev1.ConditionStatus{corev1.ConditionTrue, corev1.ConditionFalse, corev1.ConditionUnknown} {
+			ch <- prometheus.MustNewConstMetric(nodeConditionDesc, prometheus.GaugeValue,
+				boolToFloat(cond.Status == status), node.Name, string(cond.Type), strings.ToLower(string(status)))
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(nodeHealthyDesc, prometheus.GaugeValue,
+		boolToFloat(node.Status().Errors == nil), node.Name)
+	ch <- prometheus.MustNewConstMetric(nodeMaintenanceDesc, prometheus.GaugeValue,
+		boolToFloat(node.Maintenance != ""), node.Name)
+}
+
+func (c *Collector) collectServices(ctx context.Context, ch chan<- prometheus.Metric, nodes []string, names map[string]string) {
+	serviceList, err := c.talos.GetServiceList(ctx, nodes)
+	ch <- up("talos_services", err == nil)
+
+	for _, nodeService := range serviceList {
+		node := nodeName(names, nodeService.Metadata.GetHostname())
+		for _, svc := range nodeService.Services {
+			ch <- prometheus.MustNewConstMetric(serviceHealthyDesc, prometheus.GaugeValue,
+				boolToFloat(svc.GetHealth().GetHealthy()), node, svc.Id)
+			ch <- prometheus.MustNewConstMetric(serviceHealthUnknownDesc, prometheus.GaugeValue,
+				boolToFloat(svc.GetHealth().GetUnknown()), node, svc.Id)
+			ch <- prometheus.MustNewConstMetric(serviceStateDesc, prometheus.GaugeValue,
+				1, node, svc.Id, svc.State)
+		}
+	}
+}
+
+func (c *Collector) collectEtcdStatus(ctx context.Context, ch chan<- prometheus.Metric, nodes []string, names map[string]string) {
+	etcdStatusList, err := c.talos.GetEtcdStatus(ctx, nodes)
+	ch <- up("etcd_status", err == nil)
+
+	for _, etcdStatus := range etcdStatusList {
+		member := etcdStatus.GetMemberStatus()
+		if member == nil {
+			continue
+		}
+		node := nodeName(names, etcdStatus.Metadata.GetHostname())
+		id := strconv.FormatUint(member.MemberId, 16)
+
+		ch <- prometheus.MustNewConstMetric(etcdLeaderDesc, prometheus.GaugeValue,
+			boolToFloat(member.Leader != 0 && member.Leader == member.MemberId), node, id)
+		ch <- prometheus.MustNewConstMetric(etcdHasLeaderDesc, prometheus.GaugeValue,
+			boolToFloat(member.Leader != 0), node, id)
+		ch <- prometheus.MustNewConstMetric(etcdLearnerDesc, prometheus.GaugeValue,
+			boolToFloat(member.IsLearner), node, id)
+		ch <- prometheus.MustNewConstMetric(etcdDbSizeDesc, prometheus.GaugeValue,
+			float64(member.DbSize), node, id)
+		ch <- prometheus.MustNewConstMetric(etcdDbSizeInUseDesc, prometheus.GaugeValue,
+			float64(member.DbSizeInUse), node, id)
+		ch <- prometheus.MustNewConstMetric(etcdRaftIndexDesc, prometheus.GaugeValue,
+			float64(member.RaftIndex), node, id)
+	}
+}
+
+func (c *Collector) collectEtcdAlarms(ctx context.Context, ch chan<- prometheus.Metric, nodes []string) {
+	alarms, err := c.talos.GetEtcdAlarms(ctx, nodes)
+	ch <- up("etcd_alarms", err == nil)
+	if err != nil && alarms == nil {
+		return
+	}
+
+	for _, alarm := range alarms {
+		ch <- prometheus.MustNewConstMetric(etcdAlarmDesc, prometheus.GaugeValue, 1,
+			strconv.FormatUint(alarm.MemberId, 16), alarm.Alarm.String())
+	}
+	ch <- prometheus.MustNewConstMetric(etcdAlarmsDesc, prometheus.GaugeValue, float64(len(alarms)))
+}
+
+func up(source string, ok bool) prometheus.Metric {
+	if !ok {
+		fmt.Fprintf(os.Stderr, "metrics: error collecting %s\n", source)
+	}
+	return prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, boolToFloat(ok), source)
+}
+
+func nodeName(names map[string]string, address string) string {
+	if name, ok := names[address]; ok {
+		return name
+	}
+	return address
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/glbyers/epimetheus/severity"
+)
+
+// Perf is a single Nagios performance data value.
+type Perf struct {
+	Label string
+	Value float64
+	UOM   string
+}
+
+func (p Perf) String() string {
+	label := strings.NewReplacer("'", "", "=", "_").Replace(p.Label)
+	return fmt.Sprintf("'%s'=%s%s", label, strconv.FormatFloat(p.Value, 'f', -1, 64), p.UOM)
+}
+
+// nagiosText keeps a message on one line & out of the performance data, which
+// Nagios separates from the output with '|'.
+var nagiosText = strings.NewReplacer("|", "/", "\r\n", " ", "\n", " ", "\r", " ")
+
+// nagiosState maps a result to a Nagios plugin state. Checks that could not
+// query their source are UNKNOWN.
+func nagiosState(result *Result) string {
+	if result.Status != http.StatusOK && result.Status != http.StatusExpectationFailed {
+		return "UNKNOWN"
+	}
+	switch result.Severity {
+	case severity.Critical:
+		return "CRITICAL"
+	case severity.Warning:
+		return "WARNING"
+	default:
+		return "OK"
+	}
+}
+
+// nagios renders a result in the Nagios plugin output format: a single status
+// line with performance data, followed by the errors as long output.
+func nagios(result *Result) string {
+	var b strings.Builder
+
+	problems := append(slices.Clone(result.Errors), result.Warnings...)
+	for i, problem := range problems {
+		problems[i] = nagiosText.Replace(problem)
+	}
+
+	state := nagiosState(result)
+	summary := "no problems found"
+	switch len(problems) {
+	case 0:
+	case 1:
+		summary = problems[0]
+	default:
+		summary = fmt.Sprintf("%d problems found", len(problems))
+	}
+
+	fmt.Fprintf(&b, "%s %s - %s", strings.ToUpper(result.Check), state, summary)
+	if result.Perf != nil {
+		perf := make([]string, 0, len(result.Perf))
+		for _, p := range result.Perf {
+			perf = append(perf, p.String())
+		}
+		fmt.Fprintf(&b, " | %s", strings.Join(perf, " "))
+	}
+	b.WriteString("\n")
+
+	if len(problems) > 1 {
+		for i, problem := range problems {
+			label := "CRITICAL"
+			if i >= len(result.Errors) {
+				label = "WARNING"
+			}
+			fmt.Fprintf(&b, "%s: %s\n", label, problem)
+		}
+	}
+
+	return b.String()
+}
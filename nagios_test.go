@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/glbyers/epimetheus/severity"
+)
+
+func TestNagios(t *testing.T) {
+	tests := []struct {
+		name   string
+		result *Result
+		want   string
+	}{
+		{
+			name: "ok",
+			result: &Result{Check: "pods", Status: http.StatusOK, Report: &Report{},
+				Perf: []Perf{{Label: "pods", Value: 12}, {Label: "not ready", Value: 0}}},
+			want: "PODS OK - no problems found | 'pods'=12 'not ready'=0\n",
+		},
+		{
+			name: "single problem",
+			result: &Result{Check: "nodes", Status: http.StatusExpectationFailed,
+				Report: &Report{Severity: severity.Critical, Errors: []string{"Node 'worker-1' is not ready"}}},
+			want: "NODES CRITICAL - Node 'worker-1' is not ready\n",
+		},
+		{
+			name: "warning",
+			result: &Result{Check: "pvcs", Status: http.StatusOK,
+				Report: &Report{Severity: severity.Warning, Warnings: []string{"Claim 'default/data' is 95% full"}},
+				Perf:   []Perf{{Label: "used", Value: 0.95, UOM: "%"}}},
+			want: "PVCS WARNING - Claim 'default/data' is 95% full | 'used'=0.95%\n",
+		},
+		{
+			name: "several problems",
+			result: &Result{Check: "services", Status: http.StatusExpectationFailed,
+				Report: &Report{Severity: severity.Critical,
+					Errors:   []string{"Service 'etcd' is not healthy"},
+					Warnings: []string{"Service 'ext-iscsid' is not running"}}},
+			want: "SERVICES CRITICAL - 2 problems found\n" +
+				"CRITICAL: Service 'etcd' is not healthy\n" +
+				"WARNING: Service 'ext-iscsid' is not running\n",
+		},
+		{
+			name: "unavailable",
+			result: &Result{Check: "etcd-status", Status: http.StatusServiceUnavailable,
+				Report: &Report{Severity: severity.Critical, Errors: []string{"connection refused"}}},
+			want: "ETCD-STATUS UNKNOWN - connection refused\n",
+		},
+		{
+			name: "pipes & newlines",
+			result: &Result{Check: "pods", Status: http.StatusExpectationFailed,
+				Report: &Report{Severity: severity.Critical,
+					Errors: []string{"Pod 'default/web' not ready: exec a | b\nfailed"}},
+				Perf: []Perf{{Label: "pods", Value: 1}}},
+			want: "PODS CRITICAL - Pod 'default/web' not ready: exec a / b failed | 'pods'=1\n",
+		},
+		{
+			name: "pipes & newlines in long output",
+			result: &Result{Check: "pods", Status: http.StatusExpectationFailed,
+				Report: &Report{Severity: severity.Critical,
+					Errors:   []string{"first\r\nline"},
+					Warnings: []string{"a|b"}}},
+			want: "PODS CRITICAL - 2 problems found\n" +
+				"CRITICAL: first line\n" +
+				"WARNING: a/b\n",
+		},
+		{
+			name: "perf labels",
+			result: &Result{Check: "pods", Status: http.StatusOK, Report: &Report{},
+				Perf: []Perf{{Label: "it's=odd", Value: 1}}},
+			want: "PODS OK - no problems found | 'its_odd'=1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nagios(tt.result); got != tt.want {
+				t.Errorf("nagios() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package notify
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"text/template"
+	"time"
+
+	"github.com/glbyers/epimetheus/severity"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// Webhook is a receiver of notifications. Body is a text/template rendered
+// with a Notification, the notification as JSON by default. When Secret is
+// set the body is signed with HMAC-SHA256 in the X-Epimetheus-Signature
+// header. Checks limits the webhook to some checks, all of them when unset.
+type Webhook struct {
+	Name        string            `json:"name"`
+	URL         string            `json:"url"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Body        string            `json:"body,omitempty"`
+	Secret      string            `json:"secret,omitempty"`
+	Checks      []string          `json:"checks,omitempty"`
+	MinSeverity severity.Level    `json:"minSeverity,omitempty"`
+
+	template *template.Template
+}
+
+// Config lists the webhooks & how failed deliveries are retried, doubling
+// the backoff after each attempt up to MaxBackoff.
+type Config struct {
+	Webhooks   []*Webhook      `json:"webhooks"`
+	Retries    int             `json:"retries"`
+	Backoff    metav1.Duration `json:"backoff"`
+	MaxBackoff metav1.Duration `json:"maxBackoff"`
+	Timeout    metav1.Duration `json:"timeout"`
+}
+
+func Default() *Config {
+	return &Config{
+		Retries:    5,
+		Backoff:    metav1.Duration{Duration: time.Second},
+		MaxBackoff: metav1.Duration{Duration: time.Minute},
+		Timeout:    metav1.Duration{Duration: 10 * time.Second},
+	}
+}
+
+func Load(file string) (*Config, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading notification config: %w", err)
+	}
+
+	c := Default()
+	if err = yaml.UnmarshalStrict(data, c); err != nil {
+		return nil, fmt.Errorf("error parsing notification config %s: %w", file, err)
+	}
+	if err = c.validate(); err != nil {
+		return nil, fmt.Errorf("invalid notification config %s: %w", file, err)
+	}
+
+	return c, nil
+}
+
+func (c *Config) validate() error {
+	for i, webhook := range c.Webhooks {
+		if webhook.Name == "" {
+			webhook.Name = fmt.Sprintf("webhook-%d", i)
+		}
+		if u, err := url.Parse(webhook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("webhook %s: url must be http or https", webhook.Name)
+		}
+		if webhook.Body != "" {
+			tmpl, err := template.New(webhook.Name).Funcs(funcs).Parse(webhook.Body)
+			if err != nil {
+				return fmt.Errorf("webhook %s: %w", webhook.Name, err)
+			}
+			webhook.template = tmpl
+		}
+	}
+	return nil
+}
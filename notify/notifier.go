@@ -0,0 +1,201 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/glbyers/epimetheus/severity"
+)
+
+const (
+	StatusFiring   = "firing"
+	StatusResolved = "resolved"
+)
+
+// Notification is sent when a check changes severity.
+type Notification struct {
+	Check    string         `json:"check"`
+	Status   string         `json:"status"`
+	Severity severity.Level `json:"severity"`
+	Previous severity.Level `json:"previous"`
+	Errors   []string       `json:"errors,omitempty"`
+	Warnings []string       `json:"warnings,omitempty"`
+	Time     time.Time      `json:"time"`
+}
+
+var funcs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"join": strings.Join,
+}
+
+// queueSize is how many notifications may wait for delivery to a webhook
+// before further ones are dropped.
+const queueSize = 100
+
+// delivery is a notification waiting to be posted to a webhook.
+type delivery struct {
+	ctx          context.Context
+	notification Notification
+}
+
+// Notifier posts notifications to webhooks when checks change severity.
+type Notifier struct {
+	config *Config
+	client *http.Client
+
+	mu     sync.Mutex
+	last   map[string]severity.Level
+	queues map[*Webhook]chan delivery
+}
+
+func New(config *Config) *Notifier {
+	return &Notifier{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout.Duration},
+		last:   make(map[string]severity.Level),
+		queues: make(map[*Webhook]chan delivery),
+	}
+}
+
+// Update records the current severity of a check, notifying the webhooks if
+// it differs from the last one notified. A check first seen failing notifies
+// too, so that it is resolved later. Deliveries happen in the background, in
+// order for each webhook.
+func (n *Notifier) Update(ctx context.Context, notification Notification) {
+	n.mu.Lock()
+	previous, seen := n.last[notification.Check]
+	n.last[notification.Check] = notification.Severity
+	n.mu.Unlock()
+
+	if previous == notification.Severity && (seen || notification.Severity == severity.OK) {
+		return
+	}
+
+	notification.Previous = previous
+	notification.Status = StatusFiring
+	if notification.Severity == severity.OK {
+		notification.Status = StatusResolved
+	}
+
+	for _, webhook := range n.config.Webhooks {
+		if webhook.Checks != nil && !slices.Contains(webhook.Checks, notification.Check) {
+			continue
+		}
+		// resolve what was notified before, even below the minimum
+		if max(notification.Severity, previous) < webhook.MinSeverity {
+			continue
+		}
+		n.enqueue(webhook, delivery{ctx: ctx, notification: notification})
+	}
+}
+
+// enqueue queues a delivery to the webhook, starting the goroutine delivering
+// to it on first use, so that a resolution is never posted before the
+// notification it resolves.
+func (n *Notifier) enqueue(webhook *Webhook, d delivery) {
+	n.mu.Lock()
+	queue, ok := n.queues[webhook]
+	if !ok {
+		queue = make(chan delivery, queueSize)
+		n.queues[webhook] = queue
+		go func() {
+			for d := range queue {
+				n.deliver(d.ctx, webhook, d.notification)
+			}
+		}()
+	}
+	n.mu.Unlock()
+
+	select {
+	case queue <- d:
+	default:
+		fmt.Fprintf(os.Stderr, "webhook %s: queue full, dropping %s %s notification\n",
+			webhook.Name, d.notification.Check, d.notification.Status)
+	}
+}
+
+// deliver posts the notification, retrying with backoff on connection
+// errors, 429 & 5xx responses.
+func (n *Notifier) deliver(ctx context.Context, webhook *Webhook, notification Notification) {
+	body, err := render(webhook, notification)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "webhook %s: %v\n", webhook.Name, err)
+		return
+	}
+
+	backoff := n.config.Backoff.Duration
+	for attempt := 0; ; attempt++ {
+		retry, err := n.post(ctx, webhook, body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= n.config.Retries {
+			fmt.Fprintf(os.Stderr, "webhook %s: giving up on %s %s notification: %v\n",
+				webhook.Name, notification.Check, notification.Status, err)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, n.config.MaxBackoff.Duration)
+	}
+}
+
+func (n *Notifier) post(ctx context.Context, webhook *Webhook, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range webhook.Headers {
+		req.Header.Set(key, value)
+	}
+	if webhook.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(webhook.Secret))
+		mac.Write(body)
+		req.Header.Set("X-Epimetheus-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retry, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return false, nil
+}
+
+func render(webhook *Webhook, notification Notification) ([]byte, error) {
+	if webhook.template == nil {
+		return json.Marshal(notification)
+	}
+
+	var buf bytes.Buffer
+	if err := webhook.template.Execute(&buf, notification); err != nil {
+		return nil, fmt.Errorf("error rendering body: %w", err)
+	}
+	return buf.Bytes(), nil
+}
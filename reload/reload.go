@@ -0,0 +1,58 @@
+package reload
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Watch calls load whenever the modification time of any of files changes,
+// polling every interval until ctx is cancelled. Polling rather than inotify
+// copes with Kubernetes swapping the symlinks of mounted secrets & ConfigMaps.
+// Errors from load are logged, the previous state is kept & load is retried.
+func Watch(ctx context.Context, interval time.Duration, files []string, load func() error) {
+	last := modTimes(files)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current := modTimes(files)
+		if equal(last, current) {
+			continue
+		}
+
+		// retry on the next tick until the files are consistent again
+		if err := load(); err != nil {
+			fmt.Fprintf(os.Stderr, "error reloading %v: %v\n", files, err)
+			continue
+		}
+		last = current
+	}
+}
+
+func modTimes(files []string) []time.Time {
+	times := make([]time.Time, len(files))
+	for i, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			times[i] = info.ModTime()
+		}
+	}
+	return times
+}
+
+func equal(a, b []time.Time) bool {
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,250 @@
+package rules
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"time"
+
+	"github.com/glbyers/epimetheus/severity"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	RequireHealth  = "health"
+	RequireRunning = "running"
+)
+
+// Rule declares what is required of Talos services matching Service, a glob
+// pattern such as "ext-*". When Roles lists any roles, the rule only applies
+// to nodes with at least one of them. Unmet requirements are critical unless
+// Severity says otherwise.
+type Rule struct {
+	Service  string          `json:"service"`
+	Roles    []string        `json:"roles,omitempty"`
+	Require  []string        `json:"require"`
+	Severity *severity.Level `json:"severity,omitempty"`
+}
+
+// Requirement is the result of evaluating the rules for a single service.
+type Requirement struct {
+	Health   bool
+	Running  bool
+	Severity severity.Level
+}
+
+// NodeRules lists node conditions that are only warnings when failing.
+type NodeRules struct {
+	Warning []string `json:"warning"`
+}
+
+// PodRules sets how long a pod may be not ready before it is critical rather
+// than a warning.
+type PodRules struct {
+	CriticalAfter metav1.Duration `json:"criticalAfter"`
+}
+
+// WorkloadRules sets the severity of workloads that are under-replicated or
+// have a spec change their controller has yet to observe.
+type WorkloadRules struct {
+	Degraded severity.Level `json:"degraded"`
+}
+
+// LeaseRules lists the namespaces whose leader-election leases are checked in
+// addition to kube-system, such as those of operators, glob patterns of lease
+// names that aren't checked, & how long past its duration a lease may go
+// without renewal before it is stale.
+type LeaseRules struct {
+	Namespaces []string        `json:"namespaces"`
+	Exclude    []string        `json:"exclude"`
+	Tolerance  metav1.Duration `json:"tolerance"`
+}
+
+// VolumeRules sets how long a claim may be pending before it is critical
+// rather than a warning, the severity of released volumes & of claims waiting
+// for their file system to be resized, & the fraction of a volume in use
+// above which it is a warning. Volume usage is only checked when MaxUsage is
+// set.
+type VolumeRules struct {
+	CriticalAfter metav1.Duration `json:"criticalAfter"`
+	Released      severity.Level  `json:"released"`
+	ResizePending severity.Level  `json:"resizePending"`
+	MaxUsage      float64         `json:"maxUsage"`
+}
+
+// CronJobRules sets how many schedule intervals may pass without a successful
+// run before a CronJob is critical.
+type CronJobRules struct {
+	MissedSchedules float64 `json:"missedSchedules"`
+}
+
+type Rules struct {
+	Services  []Rule        `json:"services"`
+	Nodes     NodeRules     `json:"nodes"`
+	Pods      PodRules      `json:"pods"`
+	Workloads WorkloadRules `json:"workloads"`
+	Leases    LeaseRules    `json:"leases"`
+	Volumes   VolumeRules   `json:"volumes"`
+	CronJobs  CronJobRules  `json:"cronJobs"`
+}
+
+// Default reproduces the historical behaviour: services that never report
+// healthy only need to be running, everything else must be both, & every
+// failing node condition, not ready pod or degraded workload is critical.
+// Downgrading any of these to warnings is left to the rules file.
+func Default() *Rules {
+	return &Rules{
+		Services: []Rule{
+			{Service: "dashboard", Require: []string{RequireRunning}},
+			{Service: "ext-iscsid", Require: []string{RequireRunning}},
+			{Service: "ext-qemu-guest-agent", Require: []string{RequireRunning}},
+			{Service: "ext-lldpd", Require: []string{RequireRunning}},
+		},
+		Workloads: WorkloadRules{
+			Degraded: severity.Critical,
+		},
+		Leases: LeaseRules{
+			Tolerance: metav1.Duration{Duration: 10 * time.Second},
+		},
+		Volumes: VolumeRules{
+			CriticalAfter: metav1.Duration{Duration: 5 * time.Minute},
+			Released:      severity.Critical,
+			ResizePending: severity.Critical,
+		},
+		CronJobs: CronJobRules{
+			MissedSchedules: 2,
+		},
+	}
+}
+
+// Load reads rules from a YAML or JSON file, typically a mounted ConfigMap.
+// The services of the file replace the default service rules entirely, as
+// they always have, while other sections missing from the file keep their
+// defaults.
+func Load(file string) (*Rules, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading rules: %w", err)
+	}
+
+	r := Default()
+	r.Services = nil
+	if err = yaml.UnmarshalStrict(data, r); err != nil {
+		return nil, fmt.Errorf("error parsing rules %s: %w", file, err)
+	}
+	if err = r.validate(); err != nil {
+		return nil, fmt.Errorf("invalid rules %s: %w", file, err)
+	}
+
+	return r, nil
+}
+
+func (r *Rules) validate() error {
+	for i, rule := range r.Services {
+		if _, err := path.Match(rule.Service, ""); err != nil {
+			return fmt.Errorf("rule %d: bad service pattern %q: %w", i, rule.Service, err)
+		}
+		for _, req := range rule.Require {
+			if req != RequireHealth && req != RequireRunning {
+				return fmt.Errorf("rule %d: unknown requirement %q", i, req)
+			}
+		}
+	}
+	for _, pattern := range r.Leases.Exclude {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("leases: bad exclude pattern %q: %w", pattern, err)
+		}
+	}
+	for _, level := range []struct {
+		name  string
+		level severity.Level
+	}{
+		{"workloads: degraded", r.Workloads.Degraded},
+		{"volumes: released", r.Volumes.Released},
+		{"volumes: resizePending", r.Volumes.ResizePending},
+	} {
+		if level.level != severity.Warning && level.level != severity.Critical {
+			return fmt.Errorf("%s must be warning or critical, got %v", level.name, level.level)
+		}
+	}
+	if !(r.Volumes.MaxUsage >= 0 && r.Volumes.MaxUsage <= 1) {
+		return fmt.Errorf("volumes: maxUsage must be between 0 & 1, got %v", r.Volumes.MaxUsage)
+	}
+	if r.CronJobs.MissedSchedules <= 0 {
+		return fmt.Errorf("cronJobs: missedSchedules must be positive, got %v", r.CronJobs.MissedSchedules)
+	}
+	return nil
+}
+
+// Service returns the requirement of the first rule matching the service ID
+// & node roles. Services without a matching rule must be healthy & running.
+func (r *Rules) Service(id string, roles []string) Requirement {
+	for _, rule := range r.Services {
+		if ok, _ := path.Match(rule.Service, id); !ok {
+			continue
+		}
+		if len(rule.Roles) > 0 && !slices.ContainsFunc(roles, func(role string) bool {
+			return slices.Contains(rule.Roles, role)
+		}) {
+			continue
+		}
+
+		req := Requirement{
+			Health:   slices.Contains(rule.Require, RequireHealth),
+			Running:  slices.Contains(rule.Require, RequireRunning),
+			Severity: severity.Critical,
+		}
+		if rule.Severity != nil {
+			req.Severity = *rule.Severity
+		}
+		return req
+	}
+
+	return Requirement{Health: true, Running: true, Severity: severity.Critical}
+}
+
+// NodeCondition returns the severity of a failing node condition.
+func (r *Rules) NodeCondition(condition string) severity.Level {
+	if slices.Contains(r.Nodes.Warning, condition) {
+		return severity.Warning
+	}
+	return severity.Critical
+}
+
+// LeaseNamespaces returns the namespaces whose leases are checked.
+func (r *Rules) LeaseNamespaces() []string {
+	namespaces := []string{"kube-system"}
+	for _, ns := range r.Leases.Namespaces {
+		if !slices.Contains(namespaces, ns) {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
+
+// LeaseExcluded reports whether the named lease is left unchecked.
+func (r *Rules) LeaseExcluded(name string) bool {
+	return slices.ContainsFunc(r.Leases.Exclude, func(pattern string) bool {
+		ok, _ := path.Match(pattern, name)
+		return ok
+	})
+}
+
+// ClaimPending returns the severity of a claim that has been pending for d.
+func (r *Rules) ClaimPending(d time.Duration) severity.Level {
+	if d < r.Volumes.CriticalAfter.Duration {
+		return severity.Warning
+	}
+	return severity.Critical
+}
+
+// PodNotReady returns the severity of a pod that has not been ready for d.
+func (r *Rules) PodNotReady(d time.Duration) severity.Level {
+	if d < r.Pods.CriticalAfter.Duration {
+		return severity.Warning
+	}
+	return severity.Critical
+}
@@ -0,0 +1,122 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/glbyers/epimetheus/severity"
+)
+
+func TestService(t *testing.T) {
+	warning := severity.Warning
+	r := &Rules{Services: []Rule{
+		{Service: "dashboard", Require: []string{RequireRunning}},
+		{Service: "etcd", Roles: []string{"worker"}, Require: []string{}},
+		{Service: "ext-*", Require: []string{RequireRunning}, Severity: &warning},
+		{Service: "kubelet", Roles: []string{}, Require: []string{RequireHealth}},
+	}}
+
+	tests := []struct {
+		name  string
+		id    string
+		roles []string
+		want  Requirement
+	}{
+		{"no rule", "apid", []string{"controlplane"}, Requirement{Health: true, Running: true, Severity: severity.Critical}},
+		{"exact", "dashboard", nil, Requirement{Running: true, Severity: severity.Critical}},
+		{"glob with severity", "ext-iscsid", nil, Requirement{Running: true, Severity: severity.Warning}},
+		{"role matches", "etcd", []string{"worker"}, Requirement{Severity: severity.Critical}},
+		{"role differs", "etcd", []string{"controlplane"}, Requirement{Health: true, Running: true, Severity: severity.Critical}},
+		{"no roles", "etcd", nil, Requirement{Health: true, Running: true, Severity: severity.Critical}},
+		{"empty roles match any node", "kubelet", []string{"controlplane"}, Requirement{Health: true, Severity: severity.Critical}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.Service(tt.id, tt.roles); got != tt.want {
+				t.Errorf("Service(%q, %v) = %+v, want %+v", tt.id, tt.roles, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceFirstMatchWins(t *testing.T) {
+	r := &Rules{Services: []Rule{
+		{Service: "ext-lldpd", Require: []string{RequireHealth, RequireRunning}},
+		{Service: "ext-*", Require: []string{RequireRunning}},
+	}}
+
+	if got := r.Service("ext-lldpd", nil); !got.Health {
+		t.Errorf("Service(ext-lldpd) = %+v, want the first rule", got)
+	}
+	if got := r.Service("ext-iscsid", nil); got.Health {
+		t.Errorf("Service(ext-iscsid) = %+v, want the second rule", got)
+	}
+}
+
+func TestLeaseExcluded(t *testing.T) {
+	r := &Rules{Leases: LeaseRules{Exclude: []string{"apiserver-*", "cert-manager-controller"}}}
+
+	tests := map[string]bool{
+		"apiserver-6mx4sq2bhzkd":  true,
+		"cert-manager-controller": true,
+		"kube-scheduler":          false,
+		"cert-manager-cainjector": false,
+	}
+	for name, want := range tests {
+		if got := r.LeaseExcluded(name); got != want {
+			t.Errorf("LeaseExcluded(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "rules.yaml")
+	data := "services:\n- service: ext-*\n  require: [running]\n"
+	if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the services of the file replace the defaults
+	if got := r.Service("dashboard", nil); !got.Health {
+		t.Errorf("Service(dashboard) = %+v, want the default requirement", got)
+	}
+	if got := r.Service("ext-iscsid", nil); got.Health || !got.Running {
+		t.Errorf("Service(ext-iscsid) = %+v, want running only", got)
+	}
+	// other sections keep theirs
+	if got := r.CronJobs.MissedSchedules; got != Default().CronJobs.MissedSchedules {
+		t.Errorf("cronJobs.missedSchedules = %v, want the default", got)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"unknown field", "services:\n- service: ext-*\n  requires: [running]\n"},
+		{"unknown requirement", "services:\n- service: ext-*\n  require: [ready]\n"},
+		{"bad pattern", "services:\n- service: '['\n  require: [running]\n"},
+		{"bad lease exclude pattern", "leases:\n  exclude: ['[']\n"},
+		{"volume severity below warning", "volumes:\n  released: ok\n"},
+		{"bad severity", "services:\n- service: ext-*\n  require: [running]\n  severity: major\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "rules.yaml")
+			if err := os.WriteFile(file, []byte(tt.data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Load(file); err == nil {
+				t.Error("Load() succeeded, want an error")
+			}
+		})
+	}
+}
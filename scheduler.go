@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Scheduler runs checks in the background & keeps the latest result of each,
+// so that requests don't each fan out to apid.
+type Scheduler struct {
+	checks    map[string]Check
+	intervals map[string]time.Duration
+	timeout   time.Duration
+	// ready gates runs until the sources the checks read are available
+	ready func() bool
+	// hooks see each result before it is stored
+	hooks []func(name string, result *Result)
+
+	// rerun wakes a check before its interval is up
+	rerun map[string]chan struct{}
+
+	mu      sync.RWMutex
+	results map[string]*Result
+}
+
+// NewScheduler runs every check each interval, unless overridden in
+// intervals. A check with an interval of zero is not scheduled.
+func NewScheduler(checks map[string]Check, interval time.Duration, intervals map[string]time.Duration,
+	timeout time.Duration, ready func() bool) *Scheduler {
+	sc := &Scheduler{
+		checks:    checks,
+		intervals: make(map[string]time.Duration, len(checks)),
+		timeout:   timeout,
+		ready:     ready,
+		rerun:     make(map[string]chan struct{}, len(checks)),
+		results:   make(map[string]*Result, len(checks)),
+	}
+	for name := range checks {
+		sc.rerun[name] = make(chan struct{}, 1)
+		sc.intervals[name] = interval
+		if override, ok := intervals[name]; ok {
+			sc.intervals[name] = override
+		}
+	}
+	return sc
+}
+
+// OnResult registers a hook that is called with every result before it is
+// stored. Hooks may add warnings to the result. Register hooks before Start.
+func (sc *Scheduler) OnResult(hook func(name string, result *Result)) {
+	sc.hooks = append(sc.hooks, hook)
+}
+
+// Scheduled reports whether any check runs in the background.
+func (sc *Scheduler) Scheduled() bool {
+	for _, interval := range sc.intervals {
+		if interval > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Start runs the checks until ctx is cancelled.
+func (sc *Scheduler) Start(ctx context.Context) {
+	for name, check := range sc.checks {
+		if sc.intervals[name] > 0 {
+			go sc.run(ctx, name, check)
+		}
+	}
+}
+
+func (sc *Scheduler) run(ctx context.Context, name string, check Check) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		case <-sc.rerun[name]:
+		}
+
+		if !sc.ready() {
+			timer.Reset(time.Second)
+			continue
+		}
+
+		runCtx, cancel := context.WithTimeout(ctx, sc.timeout)
+		result := check(runCtx)
+		cancel()
+
+		for _, hook := range sc.hooks {
+			hook(name, result)
+		}
+
+		sc.mu.Lock()
+		sc.results[name] = result
+		sc.mu.Unlock()
+
+		timer.Reset(sc.intervals[name])
+	}
+}
+
+// Rerun asks the named checks, or every check when none are named, to run
+// again without waiting for their interval. Checks already due to run again
+// are left alone.
+func (sc *Scheduler) Rerun(names ...string) {
+	if len(names) == 0 {
+		for name := range sc.rerun {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		select {
+		case sc.rerun[name] <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Result returns the latest result of the named check, or nil when it hasn't
+// run yet or its last run is too old to trust, more than three intervals ago.
+func (sc *Scheduler) Result(name string) *Result {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+
+	result, ok := sc.results[name]
+	if !ok || time.Since(result.Time) > 3*sc.intervals[name] {
+		return nil
+	}
+	return result
+}
+
+// cached returns the scheduled result of the named check, running it live
+// when asked to with ?fresh=true, when there is no usable result or when the
+// active silences changed since. In the latter case the check is also re-run
+// in the background, so that later requests are served from the scheduler
+// again.
+func (s *Server) cached(c *gin.Context, name string, check Check) *Result {
+	if s.scheduler != nil && c.Query("fresh") != "true" {
+		result := s.scheduler.Result(name)
+		if result != nil && !result.Time.Before(s.silences.Changed()) {
+			return result
+		}
+		if result != nil {
+			s.scheduler.Rerun(name)
+		}
+	}
+	return check(c.Request.Context())
+}
+
+// parseIntervals parses a comma separated list of check=duration pairs.
+func parseIntervals(checks map[string]Check, list string) (map[string]time.Duration, error) {
+	intervals := make(map[string]time.Duration)
+	if list == "" {
+		return intervals, nil
+	}
+
+	for _, pair := range strings.Split(list, ",") {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected check=duration, got '%s'", pair)
+		}
+		if _, ok = checks[name]; !ok {
+			return nil, fmt.Errorf("unknown check '%s'", name)
+		}
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval for check '%s': %w", name, err)
+		}
+		intervals[name] = interval
+	}
+	return intervals, nil
+}
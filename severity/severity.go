@@ -0,0 +1,56 @@
+package severity
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Level is the severity of a problem found by a check. Levels are ordered, so
+// the most severe of several problems is their maximum.
+type Level int
+
+const (
+	OK Level = iota
+	Warning
+	Critical
+)
+
+var names = map[Level]string{
+	OK:       "ok",
+	Warning:  "warning",
+	Critical: "critical",
+}
+
+func (l Level) String() string {
+	if name, ok := names[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("Level(%d)", int(l))
+}
+
+func Parse(s string) (Level, error) {
+	for level, name := range names {
+		if name == s {
+			return level, nil
+		}
+	}
+	return OK, fmt.Errorf("unknown severity '%s'", s)
+}
+
+func (l Level) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.String())
+}
+
+func (l *Level) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	level, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*l = level
+	return nil
+}
@@ -0,0 +1,272 @@
+package silence
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Matchers select the problems a silence applies to. Each is a glob pattern
+// such as "worker-*" & unset matchers match anything. Role matches any of the
+// roles of the problem's node.
+type Matchers struct {
+	Check     string `json:"check,omitempty"`
+	Node      string `json:"node,omitempty"`
+	Role      string `json:"role,omitempty"`
+	Service   string `json:"service,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// Target describes a problem to match silences against.
+type Target struct {
+	Check     string
+	Node      string
+	Roles     []string
+	Service   string
+	Namespace string
+}
+
+// Silence downgrades the problems it matches between StartsAt & EndsAt.
+type Silence struct {
+	ID        string    `json:"id"`
+	Matchers  Matchers  `json:"matchers"`
+	StartsAt  time.Time `json:"startsAt"`
+	EndsAt    time.Time `json:"endsAt"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedBy string    `json:"createdBy,omitempty"`
+}
+
+// Status is pending, active or expired.
+func (s *Silence) Status(now time.Time) string {
+	switch {
+	case now.Before(s.StartsAt):
+		return "pending"
+	case now.Before(s.EndsAt):
+		return "active"
+	default:
+		return "expired"
+	}
+}
+
+func (s *Silence) Validate() error {
+	m := s.Matchers
+	if m == (Matchers{}) {
+		return errors.New("at least one matcher is required")
+	}
+	for _, pattern := range []string{m.Check, m.Node, m.Role, m.Service, m.Namespace} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("bad pattern %q: %w", pattern, err)
+		}
+	}
+	if s.EndsAt.IsZero() || !s.EndsAt.After(s.StartsAt) {
+		return errors.New("endsAt must be after startsAt")
+	}
+	return nil
+}
+
+func (s *Silence) matches(t Target) bool {
+	m := s.Matchers
+	if !match(m.Check, t.Check) || !match(m.Node, t.Node) ||
+		!match(m.Service, t.Service) || !match(m.Namespace, t.Namespace) {
+		return false
+	}
+	return m.Role == "" || slices.ContainsFunc(t.Roles, func(role string) bool {
+		return match(m.Role, role)
+	})
+}
+
+func match(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(pattern, value)
+	return ok
+}
+
+// Store keeps silences in memory, saving them to file when set. Silences are
+// forgotten a day after they expire.
+type Store struct {
+	file string
+
+	mu       sync.RWMutex
+	silences map[string]*Silence
+	updated  time.Time
+}
+
+// NewStore restores the silences saved to file, if any.
+func NewStore(file string) (*Store, error) {
+	st := &Store{file: file, silences: make(map[string]*Silence), updated: time.Now()}
+	if file == "" {
+		return st, nil
+	}
+
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return st, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading silences: %w", err)
+	}
+
+	var silences []*Silence
+	if err = json.Unmarshal(data, &silences); err != nil {
+		return nil, fmt.Errorf("error parsing silences %s: %w", file, err)
+	}
+	for _, silence := range silences {
+		st.silences[silence.ID] = silence
+	}
+	return st, nil
+}
+
+// Add validates & stores a silence, assigning its ID.
+func (st *Store) Add(silence *Silence) error {
+	if silence.StartsAt.IsZero() {
+		silence.StartsAt = time.Now()
+	}
+	if err := silence.Validate(); err != nil {
+		return err
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	silence.ID = hex.EncodeToString(id)
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.silences[silence.ID] = silence
+	st.updated = time.Now()
+	st.save()
+
+	return nil
+}
+
+// Delete removes a silence, returning false when there is none with the ID.
+func (st *Store) Delete(id string) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if _, ok := st.silences[id]; !ok {
+		return false
+	}
+	delete(st.silences, id)
+	st.updated = time.Now()
+	st.save()
+
+	return true
+}
+
+// List returns the silences ordered by start time.
+func (st *Store) List() []*Silence {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	now := time.Now()
+	silences := make([]*Silence, 0, len(st.silences))
+	for id, silence := range st.silences {
+		if now.Sub(silence.EndsAt) > 24*time.Hour {
+			delete(st.silences, id)
+			continue
+		}
+		silences = append(silences, silence)
+	}
+	sort.Slice(silences, func(i, j int) bool { return silences[i].StartsAt.Before(silences[j].StartsAt) })
+
+	return silences
+}
+
+// Match returns the first active silence matching the target, or nil.
+func (st *Store) Match(t Target) *Silence {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	now := time.Now()
+	for _, silence := range st.silences {
+		if silence.Status(now) == "active" && silence.matches(t) {
+			return silence
+		}
+	}
+	return nil
+}
+
+// NeedsRoles reports whether any silence matches on node roles, which callers
+// then need to look up.
+func (st *Store) NeedsRoles() bool {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	for _, silence := range st.silences {
+		if silence.Matchers.Role != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Changed returns the last time the set of active silences changed, through
+// an update or a silence starting or ending. Results older than this may no
+// longer reflect the active silences.
+func (st *Store) Changed() time.Time {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	now := time.Now()
+	changed := st.updated
+	for _, silence := range st.silences {
+		for _, t := range []time.Time{silence.StartsAt, silence.EndsAt} {
+			if t.After(changed) && !t.After(now) {
+				changed = t
+			}
+		}
+	}
+	return changed
+}
+
+// save writes the silences to file, replacing it atomically. Failures are
+// logged, the silences still apply until restarted. Callers hold the lock.
+func (st *Store) save() {
+	if st.file == "" {
+		return
+	}
+	if err := st.write(); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+	}
+}
+
+func (st *Store) write() error {
+	silences := make([]*Silence, 0, len(st.silences))
+	for _, silence := range st.silences {
+		silences = append(silences, silence)
+	}
+	data, err := json.Marshal(silences)
+	if err != nil {
+		return fmt.Errorf("error encoding silences: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(st.file), ".silences-*")
+	if err != nil {
+		return fmt.Errorf("error saving silences: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error saving silences: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("error saving silences: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), st.file); err != nil {
+		return fmt.Errorf("error saving silences: %w", err)
+	}
+	return nil
+}
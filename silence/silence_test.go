@@ -0,0 +1,105 @@
+package silence
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMatches(t *testing.T) {
+	target := Target{
+		Check:     "services",
+		Node:      "worker-1",
+		Roles:     []string{"worker", "storage"},
+		Service:   "ext-iscsid",
+		Namespace: "kube-system",
+	}
+
+	tests := []struct {
+		name     string
+		matchers Matchers
+		want     bool
+	}{
+		{"check", Matchers{Check: "services"}, true},
+		{"other check", Matchers{Check: "pods"}, false},
+		{"node glob", Matchers{Node: "worker-*"}, true},
+		{"other node", Matchers{Node: "cp-*"}, false},
+		{"any role", Matchers{Role: "storage"}, true},
+		{"role glob", Matchers{Role: "work*"}, true},
+		{"other role", Matchers{Role: "controlplane"}, false},
+		{"service glob", Matchers{Service: "ext-*"}, true},
+		{"other service", Matchers{Service: "kubelet"}, false},
+		{"namespace", Matchers{Namespace: "kube-system"}, true},
+		{"other namespace", Matchers{Namespace: "default"}, false},
+		{"all match", Matchers{Check: "services", Node: "worker-1", Role: "worker", Service: "ext-*"}, true},
+		{"one differs", Matchers{Check: "services", Node: "worker-2", Service: "ext-*"}, false},
+		{"bad pattern", Matchers{Node: "["}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Silence{Matchers: tt.matchers}
+			if got := s.matches(target); got != tt.want {
+				t.Errorf("matches(%+v) = %v, want %v", tt.matchers, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchesWithoutRoles(t *testing.T) {
+	s := &Silence{Matchers: Matchers{Role: "*"}}
+	if s.matches(Target{Node: "worker-1"}) {
+		t.Error("role matcher matched a target without roles")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		silence Silence
+		wantErr bool
+	}{
+		{"valid", Silence{Matchers: Matchers{Node: "worker-*"}, StartsAt: now, EndsAt: now.Add(time.Hour)}, false},
+		{"no matchers", Silence{StartsAt: now, EndsAt: now.Add(time.Hour)}, true},
+		{"bad pattern", Silence{Matchers: Matchers{Service: "["}, StartsAt: now, EndsAt: now.Add(time.Hour)}, true},
+		{"no end", Silence{Matchers: Matchers{Node: "worker-1"}, StartsAt: now}, true},
+		{"ends before start", Silence{Matchers: Matchers{Node: "worker-1"}, StartsAt: now, EndsAt: now.Add(-time.Hour)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.silence.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStoreMatch(t *testing.T) {
+	st, err := NewStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	active := &Silence{Matchers: Matchers{Node: "worker-1"}, StartsAt: now.Add(-time.Minute), EndsAt: now.Add(time.Hour)}
+	pending := &Silence{Matchers: Matchers{Node: "worker-2"}, StartsAt: now.Add(time.Hour), EndsAt: now.Add(2 * time.Hour)}
+	for _, s := range []*Silence{active, pending} {
+		if err = st.Add(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := st.Match(Target{Node: "worker-1"}); got != active {
+		t.Errorf("Match(worker-1) = %v, want the active silence", got)
+	}
+	if got := st.Match(Target{Node: "worker-2"}); got != nil {
+		t.Errorf("Match(worker-2) = %v, want no match for a pending silence", got)
+	}
+
+	st.Delete(active.ID)
+	if got := st.Match(Target{Node: "worker-1"}); got != nil {
+		t.Errorf("Match(worker-1) = %v, want no match after delete", got)
+	}
+}
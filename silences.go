@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glbyers/epimetheus/silence"
+)
+
+// silence moves the problems matched by an active silence out of the report's
+// errors & warnings, so that they no longer count towards its severity.
+func (s *Server) silence(check string, report *Report) {
+	if s.silences == nil || len(report.Problems) == 0 {
+		return
+	}
+
+	roles := make(map[string][]string)
+	if s.silences.NeedsRoles() {
+		if nodeList, err := s.k8s.GetNodes(); err == nil {
+			for _, node := range nodeList {
+				roles[node.Name] = node.Roles
+			}
+		}
+	}
+
+	report.suppress(&report.Silenced, func(problem Problem) string {
+		matched := s.silences.Match(silence.Target{
+			Check:     check,
+			Node:      problem.Node,
+			Roles:     roles[problem.Node],
+			Service:   problem.Service,
+			Namespace: problem.Namespace,
+		})
+		if matched == nil {
+			return ""
+		}
+		return "silence " + matched.ID
+	})
+}
+
+func (s *Server) getSilences(c *gin.Context) {
+	type status struct {
+		*silence.Silence
+		Status string `json:"status"`
+	}
+
+	now := time.Now()
+	response := []status{}
+	for _, sil := range s.silences.List() {
+		response = append(response, status{Silence: sil, Status: sil.Status(now)})
+	}
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+// postSilence creates a silence from a JSON body such as
+// {"matchers": {"node": "worker-1"}, "endsAt": "...", "comment": "..."},
+// starting immediately unless startsAt is given.
+func (s *Server) postSilence(c *gin.Context) {
+	var sil silence.Silence
+	if err := c.ShouldBindJSON(&sil); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	sil.CreatedBy = c.GetString(gin.AuthUserKey)
+
+	if err := s.silences.Add(&sil); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	s.rerunChecks()
+	c.IndentedJSON(http.StatusCreated, &sil)
+}
+
+func (s *Server) deleteSilence(c *gin.Context) {
+	if !s.silences.Delete(c.Param("id")) {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "silence not found"})
+		return
+	}
+	s.rerunChecks()
+	c.Status(http.StatusNoContent)
+}
+
+// rerunChecks re-runs the scheduled checks, if any, so that their results
+// reflect a change to the silences.
+func (s *Server) rerunChecks() {
+	if s.scheduler != nil {
+		s.scheduler.Rerun()
+	}
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/gin-gonic/gin"
+	"github.com/glbyers/epimetheus/events"
+	"github.com/glbyers/epimetheus/k8s"
+	"github.com/glbyers/epimetheus/severity"
+
+	"github.com/siderolabs/talos/pkg/machinery/resources/runtime"
+	"github.com/siderolabs/talos/pkg/machinery/resources/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// startWatches publishes node condition, pod readiness & Talos service
+// transitions as they happen, watching the services & machine stage of every
+// node known to Kubernetes.
+func (s *Server) startWatches(ctx context.Context) {
+	var (
+		mu      sync.Mutex
+		watches = make(map[string]context.CancelFunc)
+	)
+
+	s.k8s.OnNodeChange(func(old, new *corev1.Node) {
+		if old != nil && new != nil {
+			s.nodeConditionEvents(old, new)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if new == nil {
+			if cancel, ok := watches[old.Name]; ok {
+				cancel()
+				delete(watches, old.Name)
+			}
+			s.stages.forget(k8s.NewNode(old).Name)
+		} else if _, ok := watches[new.Name]; !ok {
+			node := k8s.NewNode(new)
+			if node.Address == "" {
+				return
+			}
+			watchCtx, cancel := context.WithCancel(ctx)
+			watches[new.Name] = cancel
+			go retryWatch(watchCtx, func(ctx context.Context) error {
+				return s.watchServices(ctx, node.Name, node.Address)
+			})
+			go retryWatch(watchCtx, func(ctx context.Context) error {
+				return s.watchMachineStatus(ctx, node.Name, node.Address)
+			})
+		}
+	})
+
+	s.k8s.OnPodChange(func(old, new *corev1.Pod) {
+		if old == nil || new == nil {
+			return
+		}
+		if wasReady, ready := podReady(old), podReady(new); wasReady != ready {
+			s.events.Publish(events.Event{
+				Type:      events.PodReady,
+				Node:      new.Spec.NodeName,
+				Namespace: new.Namespace,
+				Name:      new.Name,
+				From:      readiness(wasReady),
+				To:        readiness(ready),
+			})
+		}
+	})
+}
+
+func (s *Server) nodeConditionEvents(old, new *corev1.Node) {
+	for _, cond := range new.Status.Conditions {
+		i := slices.IndexFunc(old.Status.Conditions, func(c corev1.NodeCondition) bool {
+			return c.Type == cond.Type
+		})
+		if i >= 0 && old.Status.Conditions[i].Status == cond.Status {
+			continue
+		}
+
+		event := events.Event{
+			Type:    events.NodeCondition,
+			Node:    k8s.NewNode(new).Name,
+			Name:    string(cond.Type),
+			To:      string(cond.Status),
+			Message: cond.Message,
+		}
+		if i >= 0 {
+			event.From = string(old.Status.Conditions[i].Status)
+		}
+		s.events.Publish(event)
+	}
+}
+
+// retryWatch runs watch until ctx is cancelled, re-establishing it whenever
+// it fails.
+func retryWatch(ctx context.Context, watch func(context.Context) error) {
+	for {
+		if err := watch(ctx); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(10 * time.Second):
+		}
+	}
+}
+
+// watchServices publishes the service state transitions of one node.
+func (s *Server) watchServices(ctx context.Context, node, address string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ch := make(chan state.Event)
+	if err := s.talos.WatchServices(ctx, address, ch); err != nil {
+		return fmt.Errorf("node %s: %w", node, err)
+	}
+
+	// the states seen so far, the initial ones aren't transitions
+	states := make(map[string]string)
+	bootstrapped := false
+
+	for {
+		var event state.Event
+		select {
+		case <-ctx.Done():
+			return nil
+		case event = <-ch:
+		}
+
+		switch event.Type {
+		case state.Errored:
+			return fmt.Errorf("node %s: error watching services: %w", node, event.Error)
+		case state.Bootstrapped:
+			bootstrapped = true
+			continue
+		case state.Created, state.Updated, state.Destroyed:
+		default:
+			continue
+		}
+
+		id := event.Resource.Metadata().ID()
+		current := "removed"
+		if event.Type != state.Destroyed {
+			service, ok := event.Resource.(*v1alpha1.Service)
+			if !ok {
+				continue
+			}
+			current = serviceState(service.TypedSpec())
+		}
+
+		previous, seen := states[id]
+		if event.Type == state.Destroyed {
+			delete(states, id)
+		} else {
+			states[id] = current
+		}
+		if !bootstrapped || (seen && previous == current) {
+			continue
+		}
+
+		s.events.Publish(events.Event{
+			Type:    events.Service,
+			Node:    node,
+			Service: id,
+			From:    previous,
+			To:      current,
+		})
+	}
+}
+
+// watchMachineStatus keeps track of the Talos machine stage of one node. The
+// last stage is kept when the watch fails, as nodes that are rebooting or
+// upgrading drop off the network, until the node reports another one.
+func (s *Server) watchMachineStatus(ctx context.Context, node, address string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ch := make(chan state.Event)
+	if err := s.talos.WatchMachineStatus(ctx, address, ch); err != nil {
+		return fmt.Errorf("node %s: %w", node, err)
+	}
+
+	for {
+		var event state.Event
+		select {
+		case <-ctx.Done():
+			return nil
+		case event = <-ch:
+		}
+
+		switch event.Type {
+		case state.Errored:
+			return fmt.Errorf("node %s: error watching machine status: %w", node, event.Error)
+		case state.Created, state.Updated:
+			if status, ok := event.Resource.(*runtime.MachineStatus); ok {
+				s.stages.set(node, status.TypedSpec().Stage)
+			}
+		}
+	}
+}
+
+func serviceState(spec *v1alpha1.ServiceSpec) string {
+	switch {
+	case !spec.Running:
+		return "stopped"
+	case spec.Unknown:
+		return "running"
+	case spec.Healthy:
+		return "healthy"
+	default:
+		return "unhealthy"
+	}
+}
+
+func podReady(pod *corev1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func readiness(ready bool) string {
+	if ready {
+		return "Ready"
+	}
+	return "NotReady"
+}
+
+// alarmEvents is a Scheduler hook publishing etcd alarms as they are raised &
+// cleared, as Talos has no alarm resource to watch.
+func (s *Server) alarmEvents() func(name string, result *Result) {
+	var active map[string]Problem
+
+	return func(name string, result *Result) {
+		// an unavailable result says nothing about the alarms
+		if name != "etcd-alarms" || result.Partial {
+			return
+		}
+
+		// alarms are critical, other hooks may have added warnings
+		current := make(map[string]Problem)
+		for _, problem := range result.Problems {
+			if problem.Severity == severity.Critical {
+				current[problem.Message] = problem
+			}
+		}
+
+		// the first result only establishes the alarms already present
+		if active != nil {
+			for msg, problem := range current {
+				if _, ok := active[msg]; !ok {
+					s.events.Publish(events.Event{Type: events.EtcdAlarm, Name: problem.Name, To: "raised", Message: msg})
+				}
+			}
+			for msg, problem := range active {
+				if _, ok := current[msg]; !ok {
+					s.events.Publish(events.Event{Type: events.EtcdAlarm, Name: problem.Name, To: "cleared", Message: msg})
+				}
+			}
+		}
+		active = current
+	}
+}
+
+// getEventStream pushes events to the client as server-sent events until it
+// disconnects. ?type= takes a comma separated list of event types to send.
+func (s *Server) getEventStream(c *gin.Context) {
+	var types []string
+	if val := c.Query("type"); val != "" {
+		types = strings.Split(val, ",")
+	}
+
+	ch := s.events.Subscribe()
+	defer s.events.Unsubscribe(ch)
+
+	keepalive := time.NewTicker(30 * time.Second)
+	defer keepalive.Stop()
+
+	// stop proxies such as nginx from buffering the stream
+	c.Header("X-Accel-Buffering", "no")
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-keepalive.C:
+			c.SSEvent("keepalive", time.Now().Format(time.RFC3339))
+		case event := <-ch:
+			if types == nil || slices.Contains(types, event.Type) {
+				c.SSEvent(event.Type, event)
+			}
+		}
+		return true
+	})
+}
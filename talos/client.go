@@ -4,11 +4,14 @@ import (
 	"context"
 	"fmt"
 	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/state"
 	"github.com/siderolabs/go-retry/retry"
 	"github.com/siderolabs/talos/pkg/machinery/client"
 	"github.com/siderolabs/talos/pkg/machinery/resources/hardware"
 	"github.com/siderolabs/talos/pkg/machinery/resources/runtime"
+	"github.com/siderolabs/talos/pkg/machinery/resources/v1alpha1"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/dustin/go-humanize"
@@ -25,7 +28,10 @@ type Image struct {
 	Size      string
 	CreatedAt string
 }
+
+// Client wraps the apid client, replacing it when the connection is lost.
 type Client struct {
+	mu   sync.RWMutex
 	apid *client.Client
 }
 
@@ -37,7 +43,7 @@ func New(ctx context.Context) (*Client, error) {
 		return nil, fmt.Errorf("failed to reinitialized talos client: %v", err)
 	}
 
-	return &Client{c}, err
+	return &Client{apid: c}, err
 }
 
 func (c *Client) GetEtcdStatus(ctx context.Context, nodes []string) ([]*machine.EtcdStatus, error) {
@@ -48,9 +54,10 @@ func (c *Client) GetEtcdStatus(ctx context.Context, nodes []string) ([]*machine.
 	err := retry.Constant(10*time.Second, retry.WithUnits(100*time.Millisecond)).Retry(func() error {
 		var getErr error
 
-		etcdStatus, getErr = c.apid.EtcdStatus(nodesCtx)
+		apid := c.conn()
+		etcdStatus, getErr = apid.EtcdStatus(nodesCtx)
 		if getErr != nil {
-			err := c.refreshConnection(ctx)
+			err := c.refreshConnection(ctx, apid)
 			if err != nil {
 				return retry.ExpectedError(err)
 			}
@@ -70,6 +77,8 @@ func (c *Client) GetEtcdStatus(ctx context.Context, nodes []string) ([]*machine.
 	return etcdStatus.Messages, err
 }
 
+// GetEtcdAlarms returns the alarms raised in the cluster, each once however
+// many nodes report it.
 func (c *Client) GetEtcdAlarms(ctx context.Context, nodes []string) ([]*machine.EtcdMemberAlarm, error) {
 	var alarms []*machine.EtcdMemberAlarm
 	var etcdAlarmListResponse *machine.EtcdAlarmListResponse
@@ -79,9 +88,10 @@ func (c *Client) GetEtcdAlarms(ctx context.Context, nodes []string) ([]*machine.
 	err := retry.Constant(10*time.Second, retry.WithUnits(100*time.Millisecond)).Retry(func() error {
 		var getErr error
 
-		etcdAlarmListResponse, getErr = c.apid.EtcdAlarmList(nodesCtx)
+		apid := c.conn()
+		etcdAlarmListResponse, getErr = apid.EtcdAlarmList(nodesCtx)
 		if getErr != nil {
-			err := c.refreshConnection(ctx)
+			err := c.refreshConnection(ctx, apid)
 			if err != nil {
 				return retry.ExpectedError(err)
 			}
@@ -93,21 +103,62 @@ func (c *Client) GetEtcdAlarms(ctx context.Context, nodes []string) ([]*machine.
 	})
 
 	if err != nil {
-		fmt.Fprintf(os.Stderr, err.Error())
+		fmt.Fprintln(os.Stderr, err.Error())
 	}
 	if etcdAlarmListResponse == nil {
 		return nil, fmt.Errorf("error getting status: %w", err)
 	}
 
+	// every node returns the cluster wide alarm list, keep one of each
+	type key struct {
+		member uint64
+		alarm  machine.EtcdMemberAlarm_AlarmType
+	}
+	seen := make(map[key]bool)
 	for _, etcdAlarm := range etcdAlarmListResponse.Messages {
 		for _, alarm := range etcdAlarm.MemberAlarms {
-			alarms = append(alarms, alarm)
+			if k := (key{alarm.MemberId, alarm.Alarm}); !seen[k] {
+				seen[k] = true
+				alarms = append(alarms, alarm)
+			}
 		}
 	}
 
 	return alarms, err
 }
 
+func (c *Client) GetEtcdMembers(ctx context.Context, nodes []string) ([]*machine.EtcdMembers, error) {
+	var memberList *machine.EtcdMemberListResponse
+
+	nodesCtx := client.WithNodes(ctx, nodes...)
+
+	err := retry.Constant(10*time.Second, retry.WithUnits(100*time.Millisecond)).Retry(func() error {
+		var getErr error
+
+		apid := c.conn()
+		memberList, getErr = apid.EtcdMemberList(nodesCtx, &machine.EtcdMemberListRequest{})
+		if getErr != nil {
+			err := c.refreshConnection(ctx, apid)
+			if err != nil {
+				return retry.ExpectedError(err)
+			}
+
+			return getErr
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+	}
+	if memberList == nil {
+		return nil, fmt.Errorf("error listing etcd members: %w", err)
+	}
+
+	return memberList.Messages, err
+}
+
 func (c *Client) GetServiceList(ctx context.Context, nodes []string) ([]*machine.ServiceList, error) {
 	var serviceList *machine.ServiceListResponse
 
@@ -116,9 +167,10 @@ func (c *Client) GetServiceList(ctx context.Context, nodes []string) ([]*machine
 	err := retry.Constant(10*time.Second, retry.WithUnits(100*time.Millisecond)).Retry(func() error {
 		var getErr error
 
-		serviceList, getErr = c.apid.ServiceList(nodesCtx)
+		apid := c.conn()
+		serviceList, getErr = apid.ServiceList(nodesCtx)
 		if getErr != nil {
-			err := c.refreshConnection(ctx)
+			err := c.refreshConnection(ctx, apid)
 			if err != nil {
 				return retry.ExpectedError(err)
 			}
@@ -130,7 +182,7 @@ func (c *Client) GetServiceList(ctx context.Context, nodes []string) ([]*machine
 	})
 
 	if err != nil {
-		fmt.Fprintf(os.Stderr, err.Error())
+		fmt.Fprintln(os.Stderr, err.Error())
 	}
 	if serviceList == nil {
 		return nil, fmt.Errorf("error listing services: %w", err)
@@ -147,9 +199,10 @@ func (c *Client) GetServiceInfo(ctx context.Context, nodes []string, service str
 	err := retry.Constant(10*time.Second, retry.WithUnits(100*time.Millisecond)).Retry(func() error {
 		var getErr error
 
-		services, getErr = c.apid.ServiceInfo(nodesCtx, service)
+		apid := c.conn()
+		services, getErr = apid.ServiceInfo(nodesCtx, service)
 		if getErr != nil {
-			err := c.refreshConnection(ctx)
+			err := c.refreshConnection(ctx, apid)
 			if err != nil {
 				return retry.ExpectedError(err)
 			}
@@ -160,7 +213,7 @@ func (c *Client) GetServiceInfo(ctx context.Context, nodes []string, service str
 		return nil
 	})
 	if err != nil {
-		fmt.Fprintf(os.Stderr, err.Error())
+		fmt.Fprintln(os.Stderr, err.Error())
 	}
 	if services == nil {
 		return nil, fmt.Errorf("error getting service info: %w", err)
@@ -178,9 +231,10 @@ func (c *Client) GetImageList(ctx context.Context, nodes []string, namespace com
 	err := retry.Constant(10*time.Second, retry.WithUnits(100*time.Millisecond)).Retry(func() error {
 		var getErr error
 
-		rcv, getErr = c.apid.ImageList(nodesCtx, namespace)
+		apid := c.conn()
+		rcv, getErr = apid.ImageList(nodesCtx, namespace)
 		if getErr != nil {
-			err := c.refreshConnection(ctx)
+			err := c.refreshConnection(ctx, apid)
 			if err != nil {
 				return retry.ExpectedError(err)
 			}
@@ -191,7 +245,7 @@ func (c *Client) GetImageList(ctx context.Context, nodes []string, namespace com
 		return nil
 	})
 	if err != nil {
-		fmt.Fprintf(os.Stderr, err.Error())
+		fmt.Fprintln(os.Stderr, err.Error())
 		return nil, fmt.Errorf("error listing images: %w", err)
 	}
 
@@ -205,7 +259,7 @@ func (c *Client) GetImageList(ctx context.Context, nodes []string, namespace com
 		})
 		return nil
 	}); err != nil {
-		fmt.Fprintf(os.Stderr, err.Error())
+		fmt.Fprintln(os.Stderr, err.Error())
 		return nil, err
 	}
 
@@ -220,10 +274,11 @@ func (c *Client) GetNodeMetadata(ctx context.Context, node string) (*runtime.Pla
 	err := retry.Constant(10*time.Second, retry.WithUnits(100*time.Millisecond)).Retry(func() error {
 		var getErr error
 
-		resources, getErr = c.apid.COSI.Get(nodeCtx, resource.NewMetadata(runtime.NamespaceName,
+		apid := c.conn()
+		resources, getErr = apid.COSI.Get(nodeCtx, resource.NewMetadata(runtime.NamespaceName,
 			runtime.PlatformMetadataType, runtime.PlatformMetadataID, resource.VersionUndefined))
 		if getErr != nil {
-			err := c.refreshConnection(ctx)
+			err := c.refreshConnection(ctx, apid)
 			if err != nil {
 				return retry.ExpectedError(err)
 			}
@@ -250,11 +305,12 @@ func (c *Client) GetNodeSystemInfo(ctx context.Context, node string) (*hardware.
 	err := retry.Constant(10*time.Second, retry.WithUnits(100*time.Millisecond)).Retry(func() error {
 		var getErr error
 
-		resources, getErr = c.apid.COSI.Get(nodeCtx, resource.NewMetadata(
+		apid := c.conn()
+		resources, getErr = apid.COSI.Get(nodeCtx, resource.NewMetadata(
 			hardware.NamespaceName, hardware.SystemInformationType, hardware.SystemInformationID,
 			resource.VersionUndefined))
 		if getErr != nil {
-			err := c.refreshConnection(ctx)
+			err := c.refreshConnection(ctx, apid)
 			if err != nil {
 				return retry.ExpectedError(err)
 			}
@@ -273,7 +329,56 @@ func (c *Client) GetNodeSystemInfo(ctx context.Context, node string) (*hardware.
 	return &meta, nil
 }
 
-func (c *Client) refreshConnection(ctx context.Context) error {
+// WatchServices sends the Talos service resources of node to ch, starting with
+// the current ones followed by a state.Bootstrapped event, until ctx is
+// cancelled. A state.Errored event ends the watch.
+func (c *Client) WatchServices(ctx context.Context, node string, ch chan<- state.Event) error {
+	apid := c.conn()
+	err := apid.COSI.WatchKind(client.WithNode(ctx, node), resource.NewMetadata(
+		v1alpha1.NamespaceName, v1alpha1.ServiceType, "", resource.VersionUndefined),
+		ch, state.WithBootstrapContents(true))
+	if err != nil {
+		if refreshErr := c.refreshConnection(ctx, apid); refreshErr != nil {
+			fmt.Fprintln(os.Stderr, refreshErr.Error())
+		}
+		return fmt.Errorf("error watching services: %w", err)
+	}
+	return nil
+}
+
+// WatchMachineStatus sends the Talos MachineStatus of node to ch, starting
+// with the current one, until ctx is cancelled. A state.Errored event ends
+// the watch.
+func (c *Client) WatchMachineStatus(ctx context.Context, node string, ch chan<- state.Event) error {
+	apid := c.conn()
+	err := apid.COSI.Watch(client.WithNode(ctx, node), resource.NewMetadata(
+		runtime.NamespaceName, runtime.MachineStatusType, runtime.MachineStatusID, resource.VersionUndefined), ch)
+	if err != nil {
+		if refreshErr := c.refreshConnection(ctx, apid); refreshErr != nil {
+			fmt.Fprintln(os.Stderr, refreshErr.Error())
+		}
+		return fmt.Errorf("error watching machine status: %w", err)
+	}
+	return nil
+}
+
+// conn returns the current apid client.
+func (c *Client) conn() *client.Client {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.apid
+}
+
+// refreshConnection replaces the apid client failed, which a call just failed
+// on, when it no longer answers. Concurrent callers wait for the first to
+// finish & find failed already replaced, so only one new client is made.
+func (c *Client) refreshConnection(ctx context.Context, failed *client.Client) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.apid != failed {
+		return nil
+	}
 	if _, err := c.apid.Version(ctx); err != nil {
 		talos, err := New(ctx)
 		if err != nil {
@@ -288,5 +393,5 @@ func (c *Client) refreshConnection(ctx context.Context) error {
 }
 
 func (c *Client) TimeCheck(ctx context.Context, ntpServer string) (*timeapi.TimeResponse, error) {
-	return c.apid.TimeCheck(context.Background(), ntpServer)
+	return c.conn().TimeCheck(context.Background(), ntpServer)
 }
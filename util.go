@@ -2,6 +2,8 @@ package main
 
 import (
 	"os"
+
+	"github.com/glbyers/epimetheus/k8s"
 )
 
 func getEnv(key, fallback string) string {
@@ -10,3 +12,24 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// addresses returns the internal address of each node, as used to target
+// Talos API calls.
+func addresses(nodes []*k8s.Node) []string {
+	var addrs []string
+	for _, node := range nodes {
+		addrs = append(addrs, node.Address)
+	}
+	return addrs
+}
+
+// nodeName maps the address Talos reports a response from back to the name
+// of the node.
+func nodeName(nodes []*k8s.Node, address string) string {
+	for _, node := range nodes {
+		if node.Address == address {
+			return node.Name
+		}
+	}
+	return address
+}
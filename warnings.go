@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glbyers/epimetheus/k8s"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// eventWindow is how far back events are attached to failing checks
+	eventWindow = time.Hour
+	// maxObjectEvents is how many events are attached per failing object
+	maxObjectEvents = 5
+)
+
+// recentEvents returns a lookup of the latest Warning events in namespace by
+// the object they are about. Events are only fetched on the first lookup, as
+// they are only wanted for failing objects.
+func (s *Server) recentEvents(ctx context.Context, namespace string) func(kind, namespace, name string) []*k8s.Event {
+	var (
+		fetched bool
+		events  []*k8s.Event
+	)
+
+	return func(kind, ns, name string) []*k8s.Event {
+		if !fetched {
+			fetched = true
+			var err error
+			filter := k8s.EventFilter{Namespace: namespace, Since: time.Now().Add(-eventWindow)}
+			if events, _, err = s.k8s.GetWarningEvents(ctx, filter); err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+			}
+		}
+
+		var matched []*k8s.Event
+		for _, event := range events {
+			if event.Regarding(kind, ns, name) && len(matched) < maxObjectEvents {
+				matched = append(matched, event)
+			}
+		}
+		return matched
+	}
+}
+
+// eventFilter parses ?since=, a positive duration defaulting to an hour, &
+// ?reason=, a comma separated list of reasons. A single reason is selected by
+// the API server, several are returned to be matched by the caller.
+func eventFilter(c *gin.Context) (filter k8s.EventFilter, reasons []string, err error) {
+	window := eventWindow
+	if val := c.Query("since"); val != "" {
+		if window, err = time.ParseDuration(val); err != nil {
+			return filter, nil, fmt.Errorf("invalid since: %w", err)
+		}
+		if window <= 0 {
+			return filter, nil, fmt.Errorf("invalid since: %s is not positive", val)
+		}
+	}
+	filter.Since = time.Now().Add(-window)
+
+	if val := c.Query("reason"); val != "" {
+		reasons = strings.Split(val, ",")
+	}
+	if len(reasons) == 1 {
+		filter.Reason, reasons = reasons[0], nil
+	}
+	return filter, reasons, nil
+}
+
+// respondEvents renders the events matching reasons, when set, flagging with
+// the X-Events-Truncated header a list that was cut short.
+func respondEvents(c *gin.Context, events []*k8s.Event, reasons []string, truncated bool) {
+	response := []*k8s.Event{}
+	for _, event := range events {
+		if reasons == nil || slices.Contains(reasons, event.Reason) {
+			response = append(response, event)
+		}
+	}
+	if truncated {
+		c.Header("X-Events-Truncated", "true")
+	}
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+// getEvents returns the recent Warning events of the cluster, or of one
+// namespace with ?namespace=, most recent first.
+func (s *Server) getEvents(c *gin.Context) {
+	filter, reasons, err := eventFilter(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.Namespace = c.Query("namespace")
+
+	events, truncated, err := s.k8s.GetWarningEvents(c.Request.Context(), filter)
+	if err != nil {
+		c.IndentedJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	respondEvents(c, events, reasons, truncated)
+}
+
+// getNodeEvents returns the recent Warning events about a node & the pods
+// running on it, most recent first.
+func (s *Server) getNodeEvents(c *gin.Context) {
+	filter, reasons, err := eventFilter(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	node, err := s.k8s.GetNode(c.Param("name"))
+	if err != nil {
+		c.IndentedJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	pods, err := s.k8s.GetPods("", metav1.ListOptions{FieldSelector: "spec.nodeName=" + node.Node.Name})
+	if err != nil {
+		c.IndentedJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+
+	nodeFilter := filter
+	nodeFilter.Kind, nodeFilter.Name = "Node", node.Node.Name
+	events, truncated, err := s.k8s.GetWarningEvents(c.Request.Context(), nodeFilter)
+	if err != nil {
+		c.IndentedJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	// events can't be selected by the node of a pod, so the pod events of
+	// each namespace with pods on the node are matched against those pods
+	onNode := make(map[string]bool)
+	var namespaces []string
+	for _, pod := range pods {
+		onNode[pod.Namespace+"/"+pod.Name] = true
+		if !slices.Contains(namespaces, pod.Namespace) {
+			namespaces = append(namespaces, pod.Namespace)
+		}
+	}
+	for _, ns := range namespaces {
+		podFilter := filter
+		podFilter.Namespace, podFilter.Kind = ns, "Pod"
+		podEvents, podsTruncated, err := s.k8s.GetWarningEvents(c.Request.Context(), podFilter)
+		if err != nil {
+			c.IndentedJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		truncated = truncated || podsTruncated
+		for _, event := range podEvents {
+			if onNode[event.Namespace+"/"+event.Name] {
+				events = append(events, event)
+			}
+		}
+	}
+	slices.SortFunc(events, func(a, b *k8s.Event) int {
+		return b.LastTimestamp.Compare(a.LastTimestamp)
+	})
+	respondEvents(c, events, reasons, truncated)
+}